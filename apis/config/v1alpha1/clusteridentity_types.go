@@ -0,0 +1,170 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionTypeReady is true when a cluster name is known and can be
+	// copied into injectable namespaces.
+	ConditionTypeReady = "Ready"
+	// ConditionTypeDegraded is true when the latest detection attempt failed.
+	ConditionTypeDegraded = "Degraded"
+	// ConditionTypeConflict is true when strategies disagree on the cluster
+	// name in consensus mode. The reason is StrategiesOutvoted if a cluster
+	// name still reached the quorum and StrategiesDisagree if none did.
+	// Existing ConfigMaps are not overwritten while strategies disagree.
+	ConditionTypeConflict = "Conflict"
+
+	ReasonDetected           = "Detected"
+	ReasonDetectionFailed    = "DetectionFailed"
+	ReasonStrategiesDisagree = "StrategiesDisagree"
+	ReasonStrategiesOutvoted = "StrategiesOutvoted"
+)
+
+// OverridePolicy controls how values pinned in the spec are combined with the
+// detected values.
+// +kubebuilder:validation:Enum=Override;Fallback
+type OverridePolicy string
+
+const (
+	// OverridePolicyOverride uses the values pinned in the spec instead of the
+	// detected values.
+	OverridePolicyOverride OverridePolicy = "Override"
+	// OverridePolicyFallback only uses the values pinned in the spec when no
+	// cluster name could be detected.
+	OverridePolicyFallback OverridePolicy = "Fallback"
+)
+
+// ClusterIdentitySpec defines the desired state of ClusterIdentity
+type ClusterIdentitySpec struct {
+	// ClusterName pins the name of the cluster.
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// Attributes pins additional identity attributes that are published next
+	// to the cluster name. Well-known keys like cloudProvider, region, zones,
+	// account, project, environment and kubernetesVersion set the matching
+	// status field. Zones are given as a comma separated list.
+	// +optional
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// OverridePolicy controls whether the pinned values override detection or
+	// are only used as a fallback when no cluster name could be detected.
+	// +kubebuilder:default=Override
+	// +optional
+	OverridePolicy OverridePolicy `json:"overridePolicy,omitempty"`
+}
+
+// ClusterIdentityStatus defines the observed state of ClusterIdentity
+type ClusterIdentityStatus struct {
+	// ClusterName is the detected name of the cluster.
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// ClusterID is the UID of the kube-system namespace. It is unique and
+	// stable for the lifetime of the cluster.
+	// +optional
+	ClusterID string `json:"clusterID,omitempty"`
+
+	// Strategy is the name of the strategy that detected the cluster name.
+	// It is "spec" when the cluster name is pinned in the spec.
+	// +optional
+	Strategy string `json:"strategy,omitempty"`
+
+	// CloudProvider is the cloud the cluster runs in, i.e. aws, azure or gcp.
+	// +optional
+	CloudProvider string `json:"cloudProvider,omitempty"`
+
+	// Region is the cloud region the cluster runs in.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Zones are the availability zones the nodes of the cluster run in.
+	// +optional
+	Zones []string `json:"zones,omitempty"`
+
+	// Account is the cloud account the cluster runs in, e.g. the Azure
+	// subscription ID.
+	// +optional
+	Account string `json:"account,omitempty"`
+
+	// Project is the GCP project the cluster runs in.
+	// +optional
+	Project string `json:"project,omitempty"`
+
+	// Environment is the environment of the cluster, e.g. prod. It can only
+	// be pinned in the spec.
+	// +optional
+	Environment string `json:"environment,omitempty"`
+
+	// KubernetesVersion is the Kubernetes version of the control plane.
+	// +optional
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// Attributes are additional identity attributes without a dedicated field
+	// published next to the cluster name.
+	// +optional
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// LastObservedTime is the last time the cluster name was successfully
+	// detected.
+	// +optional
+	LastObservedTime *metav1.Time `json:"lastObservedTime,omitempty"`
+
+	// ObservedGeneration is the generation last processed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the current state of the detection.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Cluster Name",type=string,JSONPath=`.status.clusterName`
+//+kubebuilder:printcolumn:name="Strategy",type=string,JSONPath=`.status.strategy`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterIdentity is the Schema for the clusteridentities API
+type ClusterIdentity struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterIdentitySpec   `json:"spec,omitempty"`
+	Status ClusterIdentityStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterIdentityList contains a list of ClusterIdentity
+type ClusterIdentityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterIdentity `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterIdentity{}, &ClusterIdentityList{})
+}
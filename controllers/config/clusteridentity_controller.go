@@ -0,0 +1,364 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	configv1alpha1 "github.com/lunarway/cluster-identity-controller/apis/config/v1alpha1"
+	"github.com/lunarway/cluster-identity-controller/internal/operator"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// specStrategy is reported as the strategy when the cluster name is pinned
+	// in the ClusterIdentity spec.
+	specStrategy = "spec"
+
+	systemNamespace   = "kube-system"
+	kubeadmConfigName = "kubeadm-config"
+)
+
+// ClusterIdentityReconciler reconciles a ClusterIdentity object
+type ClusterIdentityReconciler struct {
+	// Client must read ConfigMaps directly from the API server as the
+	// kubeadm-config ConfigMap is not in the cache of the manager.
+	client.Client
+	// ClusterIdentityName is the name of the ClusterIdentity object created
+	// when the manager starts.
+	ClusterIdentityName string
+	ClusterNameFinder   *operator.ClusterNameFinder
+	// ResyncPeriod is how often the cluster name is detected again after a
+	// successful detection.
+	ResyncPeriod time.Duration
+	Recorder     record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=config.lunar.tech,resources=clusteridentities,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=config.lunar.tech,resources=clusteridentities/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,resourceNames=kubeadm-config,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.openshift.io,resources=infrastructures,verbs=get
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile detects the cluster name and records the result in the status of
+// the ClusterIdentity.
+func (r *ClusterIdentityReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("Reconciling cluster identity")
+
+	var identity configv1alpha1.ClusterIdentity
+	err := r.Client.Get(ctx, req.NamespacedName, &identity)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		if req.Name != r.ClusterIdentityName {
+			return ctrl.Result{Requeue: false}, nil
+		}
+		// the ClusterIdentity was deleted. It is created again and reconciled
+		// through the create event.
+		logger.Info("Cluster identity not found, creating it")
+		return ctrl.Result{}, r.ensureClusterIdentity(ctx)
+	}
+
+	detection, detectErr := r.detect(ctx, identity.Spec)
+	if detectErr != nil {
+		logger.Error(detectErr, "Failed to detect cluster name")
+		setDetectionFailed(&identity, detectErr)
+	} else {
+		setDetected(&identity, detection)
+	}
+	var conflictErr *operator.ConflictError
+	switch {
+	case errors.As(detectErr, &conflictErr):
+		setConflict(&identity, configv1alpha1.ReasonStrategiesDisagree, conflictErr.Error())
+		r.Recorder.Event(&identity, corev1.EventTypeWarning, configv1alpha1.ReasonStrategiesDisagree, conflictErr.Error())
+	case detectErr == nil && len(detection.Outvoted) > 0:
+		message := outvotedMessage(detection)
+		setConflict(&identity, configv1alpha1.ReasonStrategiesOutvoted, message)
+		r.Recorder.Event(&identity, corev1.EventTypeWarning, configv1alpha1.ReasonStrategiesOutvoted, message)
+	default:
+		setNoConflict(&identity)
+	}
+	identity.Status.ObservedGeneration = identity.Generation
+
+	err = r.Client.Status().Update(ctx, &identity)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("update status of cluster identity '%s': %w", identity.Name, err)
+	}
+
+	if detectErr != nil {
+		return ctrl.Result{}, detectErr
+	}
+
+	logger.Info("Completed reconciliation of cluster identity", "clusterName", detection.ClusterName, "strategy", detection.Strategy)
+
+	return ctrl.Result{RequeueAfter: r.ResyncPeriod}, nil
+}
+
+// detect returns the cluster identity taking the values pinned in the spec
+// into account.
+func (r *ClusterIdentityReconciler) detect(ctx context.Context, spec configv1alpha1.ClusterIdentitySpec) (operator.Detection, error) {
+	pinned := operator.Detection{
+		Identity: operator.Identity{ClusterName: spec.ClusterName},
+		Strategy: specStrategy,
+	}
+	setAttributes(&pinned.Identity, spec.Attributes)
+
+	if spec.OverridePolicy == configv1alpha1.OverridePolicyFallback {
+		detection, err := r.ClusterNameFinder.Detect(ctx, r.Client)
+		if errors.Is(err, operator.ErrClusterNameNotDetected) && pinned.ClusterName != "" {
+			return r.withClusterID(ctx, pinned)
+		}
+		if err != nil {
+			return operator.Detection{}, err
+		}
+		setFallbackAttributes(&detection.Identity, spec.Attributes)
+		return detection, nil
+	}
+
+	if pinned.ClusterName != "" {
+		return r.withClusterID(ctx, pinned)
+	}
+
+	detection, err := r.ClusterNameFinder.Detect(ctx, r.Client)
+	if err != nil {
+		return operator.Detection{}, err
+	}
+	setAttributes(&detection.Identity, spec.Attributes)
+
+	return detection, nil
+}
+
+// withClusterID sets the cluster ID on a detection that did not come from the
+// ClusterNameFinder. The cluster ID cannot be pinned.
+func (r *ClusterIdentityReconciler) withClusterID(ctx context.Context, detection operator.Detection) (operator.Detection, error) {
+	clusterID, err := operator.GetClusterID(ctx, r.Client)
+	if err != nil {
+		return operator.Detection{}, err
+	}
+	detection.ClusterID = clusterID
+	return detection, nil
+}
+
+// setAttributes sets the pinned attributes on the identity taking precedence
+// over the detected values.
+func setAttributes(identity *operator.Identity, pinned map[string]string) {
+	for key, value := range pinned {
+		identity.SetAttribute(key, value)
+	}
+}
+
+// setFallbackAttributes sets the pinned attributes on the identity that were
+// not detected.
+func setFallbackAttributes(identity *operator.Identity, pinned map[string]string) {
+	detected := identity.Data()
+	for key, value := range pinned {
+		if detected[key] == "" {
+			identity.SetAttribute(key, value)
+		}
+	}
+}
+
+func setDetected(identity *configv1alpha1.ClusterIdentity, detection operator.Detection) {
+	now := metav1.Now()
+	identity.Status.ClusterName = detection.ClusterName
+	identity.Status.ClusterID = detection.ClusterID
+	identity.Status.Strategy = detection.Strategy
+	identity.Status.CloudProvider = detection.CloudProvider
+	identity.Status.Region = detection.Region
+	identity.Status.Zones = detection.Zones
+	identity.Status.Account = detection.Account
+	identity.Status.Project = detection.Project
+	identity.Status.Environment = detection.Environment
+	identity.Status.KubernetesVersion = detection.KubernetesVersion
+	identity.Status.Attributes = detection.Attributes
+	identity.Status.LastObservedTime = &now
+
+	meta.SetStatusCondition(&identity.Status.Conditions, metav1.Condition{
+		Type:               configv1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: identity.Generation,
+		Reason:             configv1alpha1.ReasonDetected,
+		Message:            fmt.Sprintf("Cluster name detected by strategy '%s'", detection.Strategy),
+	})
+	meta.SetStatusCondition(&identity.Status.Conditions, metav1.Condition{
+		Type:               configv1alpha1.ConditionTypeDegraded,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: identity.Generation,
+		Reason:             configv1alpha1.ReasonDetected,
+	})
+}
+
+// setDetectionFailed marks the identity as degraded. A previously detected
+// cluster name is kept so namespaces can still be injected.
+func setDetectionFailed(identity *configv1alpha1.ClusterIdentity, err error) {
+	meta.SetStatusCondition(&identity.Status.Conditions, metav1.Condition{
+		Type:               configv1alpha1.ConditionTypeDegraded,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: identity.Generation,
+		Reason:             configv1alpha1.ReasonDetectionFailed,
+		Message:            err.Error(),
+	})
+
+	if identity.Status.ClusterName != "" {
+		return
+	}
+	meta.SetStatusCondition(&identity.Status.Conditions, metav1.Condition{
+		Type:               configv1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: identity.Generation,
+		Reason:             configv1alpha1.ReasonDetectionFailed,
+		Message:            err.Error(),
+	})
+}
+
+// setConflict marks the identity as conflicting. Existing ConfigMaps are not
+// overwritten if the reason is StrategiesDisagree.
+func setConflict(identity *configv1alpha1.ClusterIdentity, reason, message string) {
+	meta.SetStatusCondition(&identity.Status.Conditions, metav1.Condition{
+		Type:               configv1alpha1.ConditionTypeConflict,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: identity.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// outvotedMessage describes the strategies that disagreed with the cluster name
+// that reached the quorum.
+func outvotedMessage(detection operator.Detection) string {
+	outvoted := make([]string, 0, len(detection.Outvoted))
+	for _, candidate := range detection.Outvoted {
+		outvoted = append(outvoted, candidate.String())
+	}
+	accepted := operator.Candidate{
+		ClusterName: detection.ClusterName,
+		Strategies:  strings.Split(detection.Strategy, ","),
+	}
+	return fmt.Sprintf("strategies disagree on the cluster name: %s reached the quorum and outvoted %s", accepted, strings.Join(outvoted, ", "))
+}
+
+// setNoConflict clears a previously reported conflict. The condition is only
+// added once a conflict has been reported.
+func setNoConflict(identity *configv1alpha1.ClusterIdentity) {
+	if meta.FindStatusCondition(identity.Status.Conditions, configv1alpha1.ConditionTypeConflict) == nil {
+		return
+	}
+	meta.SetStatusCondition(&identity.Status.Conditions, metav1.Condition{
+		Type:               configv1alpha1.ConditionTypeConflict,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: identity.Generation,
+		Reason:             configv1alpha1.ReasonDetected,
+	})
+}
+
+// ensureClusterIdentity creates the ClusterIdentity object if it does not
+// exist yet.
+func (r *ClusterIdentityReconciler) ensureClusterIdentity(ctx context.Context) error {
+	err := r.Client.Create(ctx, &configv1alpha1.ClusterIdentity{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: r.ClusterIdentityName,
+		},
+	})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create cluster identity '%s': %w", r.ClusterIdentityName, err)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterIdentityReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.Add(manager.RunnableFunc(r.ensureClusterIdentity))
+	if err != nil {
+		return err
+	}
+
+	// the cache of the manager only holds managed ConfigMaps so kubeadm-config
+	// is watched through a cache of its own
+	kubeadmConfigCache, err := cache.New(mgr.GetConfig(), cache.Options{
+		HTTPClient: mgr.GetHTTPClient(),
+		Scheme:     mgr.GetScheme(),
+		Mapper:     mgr.GetRESTMapper(),
+		Namespaces: []string{systemNamespace},
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.ConfigMap{}: {Field: fields.OneTermEqualSelector("metadata.name", kubeadmConfigName)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create kubeadm-config cache: %w", err)
+	}
+	err = mgr.Add(kubeadmConfigCache)
+	if err != nil {
+		return err
+	}
+
+	// the manager is expected to only cache pods, deployments and daemonsets
+	// in kube-system, see main.go. The predicate keeps other namespaces out
+	// if it does not.
+	kubeSystem := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == systemNamespace
+	})
+	sourceChanged := handler.EnqueueRequestsFromMapFunc(r.sourceChanged)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&configv1alpha1.ClusterIdentity{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Node{}, sourceChanged, builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		Watches(&corev1.Pod{}, sourceChanged, builder.WithPredicates(kubeSystem, predicate.GenerationChangedPredicate{})).
+		Watches(&appsv1.Deployment{}, sourceChanged, builder.WithPredicates(kubeSystem, predicate.GenerationChangedPredicate{})).
+		Watches(&appsv1.DaemonSet{}, sourceChanged, builder.WithPredicates(kubeSystem, predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Namespace{}, sourceChanged, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetName() == systemNamespace
+		}), predicate.GenerationChangedPredicate{})).
+		WatchesRawSource(source.Kind(kubeadmConfigCache, &corev1.ConfigMap{}), sourceChanged).
+		Complete(r)
+}
+
+// sourceChanged detects the cluster identity again when one of the objects the
+// strategies read from changes.
+func (r *ClusterIdentityReconciler) sourceChanged(ctx context.Context, obj client.Object) []reconcile.Request {
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: r.ClusterIdentityName}},
+	}
+}
@@ -0,0 +1,364 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	configv1alpha1 "github.com/lunarway/cluster-identity-controller/apis/config/v1alpha1"
+	"github.com/lunarway/cluster-identity-controller/internal/operator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func setupClusterIdentityReconciler(t *testing.T, identity configv1alpha1.ClusterIdentity, objects []client.Object) (*ClusterIdentityReconciler, client.Client) {
+	t.Helper()
+
+	s := scheme.Scheme
+	utilruntime.Must(configv1alpha1.AddToScheme(s))
+
+	client := fake.NewClientBuilder().
+		WithObjects(objects...).
+		WithObjects(&identity).
+		WithStatusSubresource(&identity).
+		Build()
+
+	clusterNameFinder, err := operator.NewClusterNameFinder(operator.ClusterNameFinderOptions{})
+	require.NoError(t, err)
+
+	reconciler := &ClusterIdentityReconciler{
+		Client:              client,
+		ClusterIdentityName: identity.Name,
+		ClusterNameFinder:   clusterNameFinder,
+		ResyncPeriod:        time.Minute,
+		Recorder:            record.NewFakeRecorder(10),
+	}
+
+	return reconciler, client
+}
+
+func TestClusterIdentityController(t *testing.T) {
+	logf.SetLogger(zap.New(zap.WriteTo(os.Stdout), zap.UseDevMode(true)))
+	var (
+		clusterName = "k8s-202109170606.lunar.tech"
+		request     = ctrl.Request{NamespacedName: types.NamespacedName{Name: "cluster"}}
+	)
+
+	t.Run("record detected cluster name in status", func(t *testing.T) {
+		reconciler, client := setupClusterIdentityReconciler(t, clusterIdentity(), []client.Object{
+			nodeWithClusterNameLabel(clusterName),
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{RequeueAfter: time.Minute}, result)
+
+		identity := getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, clusterName, identity.Status.ClusterName)
+		assert.Equal(t, "nodeLabel", identity.Status.Strategy)
+		assert.NotNil(t, identity.Status.LastObservedTime)
+		assert.True(t, meta.IsStatusConditionTrue(identity.Status.Conditions, configv1alpha1.ConditionTypeReady))
+		assert.True(t, meta.IsStatusConditionFalse(identity.Status.Conditions, configv1alpha1.ConditionTypeDegraded))
+	})
+
+	t.Run("record kube-system namespace UID as cluster ID", func(t *testing.T) {
+		kubeSystem := kubeSystemNamespace()
+		reconciler, client := setupClusterIdentityReconciler(t, clusterIdentity(), []client.Object{
+			nodeWithClusterNameLabel(clusterName),
+			kubeSystem,
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+
+		assert.NoError(t, err)
+
+		identity := getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, clusterName, identity.Status.ClusterName)
+		assert.Equal(t, string(kubeSystem.UID), identity.Status.ClusterID)
+	})
+
+	t.Run("mark not ready when cluster name cannot be detected", func(t *testing.T) {
+		reconciler, client := setupClusterIdentityReconciler(t, clusterIdentity(), nil)
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+
+		assert.EqualError(t, err, "could not detect cluster name")
+
+		identity := getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, "", identity.Status.ClusterName)
+		assert.True(t, meta.IsStatusConditionFalse(identity.Status.Conditions, configv1alpha1.ConditionTypeReady))
+		assert.True(t, meta.IsStatusConditionTrue(identity.Status.Conditions, configv1alpha1.ConditionTypeDegraded))
+	})
+
+	t.Run("keep previously detected cluster name when detection fails", func(t *testing.T) {
+		identity := clusterIdentity()
+		identity.Status.ClusterName = clusterName
+		identity.Status.Conditions = []metav1.Condition{
+			{
+				Type:   configv1alpha1.ConditionTypeReady,
+				Status: metav1.ConditionTrue,
+				Reason: configv1alpha1.ReasonDetected,
+			},
+		}
+		reconciler, client := setupClusterIdentityReconciler(t, identity, nil)
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+
+		assert.EqualError(t, err, "could not detect cluster name")
+
+		identity = getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, clusterName, identity.Status.ClusterName)
+		assert.True(t, meta.IsStatusConditionTrue(identity.Status.Conditions, configv1alpha1.ConditionTypeReady))
+		assert.True(t, meta.IsStatusConditionTrue(identity.Status.Conditions, configv1alpha1.ConditionTypeDegraded))
+	})
+
+	t.Run("use pinned cluster name instead of detected cluster name", func(t *testing.T) {
+		identity := clusterIdentity()
+		identity.Spec = configv1alpha1.ClusterIdentitySpec{
+			ClusterName: "pinned",
+			Attributes:  map[string]string{"environment": "prod"},
+		}
+		kubeSystem := kubeSystemNamespace()
+		reconciler, client := setupClusterIdentityReconciler(t, identity, []client.Object{
+			nodeWithClusterNameLabel(clusterName),
+			kubeSystem,
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+
+		assert.NoError(t, err)
+
+		identity = getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, "pinned", identity.Status.ClusterName)
+		assert.Equal(t, string(kubeSystem.UID), identity.Status.ClusterID)
+		assert.Equal(t, "spec", identity.Status.Strategy)
+		assert.Equal(t, "prod", identity.Status.Environment)
+	})
+
+	t.Run("add pinned attributes to detected cluster name", func(t *testing.T) {
+		identity := clusterIdentity()
+		identity.Spec = configv1alpha1.ClusterIdentitySpec{
+			Attributes: map[string]string{"environment": "prod"},
+		}
+		reconciler, client := setupClusterIdentityReconciler(t, identity, []client.Object{
+			nodeWithClusterNameLabel(clusterName),
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+
+		assert.NoError(t, err)
+
+		identity = getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, clusterName, identity.Status.ClusterName)
+		assert.Equal(t, "nodeLabel", identity.Status.Strategy)
+		assert.Equal(t, "prod", identity.Status.Environment)
+	})
+
+	t.Run("prefer detected cluster name over fallback", func(t *testing.T) {
+		identity := clusterIdentity()
+		identity.Spec = configv1alpha1.ClusterIdentitySpec{
+			ClusterName:    "pinned",
+			OverridePolicy: configv1alpha1.OverridePolicyFallback,
+		}
+		reconciler, client := setupClusterIdentityReconciler(t, identity, []client.Object{
+			nodeWithClusterNameLabel(clusterName),
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+
+		assert.NoError(t, err)
+
+		identity = getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, clusterName, identity.Status.ClusterName)
+		assert.Equal(t, "nodeLabel", identity.Status.Strategy)
+	})
+
+	t.Run("add fallback attributes that were not detected", func(t *testing.T) {
+		identity := clusterIdentity()
+		identity.Spec = configv1alpha1.ClusterIdentitySpec{
+			ClusterName: "pinned",
+			Attributes: map[string]string{
+				"environment":   "prod",
+				"zones":         "pinned-zone",
+				"resourceGroup": "pinned-group",
+			},
+			OverridePolicy: configv1alpha1.OverridePolicyFallback,
+		}
+		node := nodeWithClusterNameLabel(clusterName)
+		node.Labels["topology.kubernetes.io/zone"] = "eu-west-1a"
+		reconciler, client := setupClusterIdentityReconciler(t, identity, []client.Object{node})
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+
+		assert.NoError(t, err)
+
+		identity = getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, clusterName, identity.Status.ClusterName)
+		assert.Equal(t, "nodeLabel", identity.Status.Strategy)
+		assert.Equal(t, "prod", identity.Status.Environment)
+		assert.Equal(t, []string{"eu-west-1a"}, identity.Status.Zones)
+		assert.Equal(t, map[string]string{"resourceGroup": "pinned-group"}, identity.Status.Attributes)
+	})
+
+	t.Run("use fallback when cluster name cannot be detected", func(t *testing.T) {
+		identity := clusterIdentity()
+		identity.Spec = configv1alpha1.ClusterIdentitySpec{
+			ClusterName:    "pinned",
+			OverridePolicy: configv1alpha1.OverridePolicyFallback,
+		}
+		reconciler, client := setupClusterIdentityReconciler(t, identity, nil)
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+
+		assert.NoError(t, err)
+
+		identity = getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, "pinned", identity.Status.ClusterName)
+		assert.Equal(t, "spec", identity.Status.Strategy)
+		assert.True(t, meta.IsStatusConditionTrue(identity.Status.Conditions, configv1alpha1.ConditionTypeReady))
+	})
+
+	t.Run("report conflict when strategies disagree", func(t *testing.T) {
+		identity := clusterIdentity()
+		identity.Status.ClusterName = clusterName
+		identity.Status.Conditions = []metav1.Condition{
+			{
+				Type:   configv1alpha1.ConditionTypeReady,
+				Status: metav1.ConditionTrue,
+				Reason: configv1alpha1.ReasonDetected,
+			},
+		}
+		reconciler, client := setupClusterIdentityReconciler(t, identity, []client.Object{
+			nodeWithConflictingClusterNames(clusterName, "other"),
+		})
+		clusterNameFinder, err := operator.NewClusterNameFinder(operator.ClusterNameFinderOptions{
+			Strategies: []string{"nodeLabel", "capi"},
+			Quorum:     1,
+		})
+		require.NoError(t, err)
+		reconciler.ClusterNameFinder = clusterNameFinder
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+
+		_, err = reconciler.Reconcile(context.Background(), request)
+
+		expectedMessage := fmt.Sprintf("strategies disagree on the cluster name: '%s' (nodeLabel), 'other' (capi)", clusterName)
+		assert.EqualError(t, err, expectedMessage)
+
+		identity = getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, clusterName, identity.Status.ClusterName)
+		assert.True(t, meta.IsStatusConditionTrue(identity.Status.Conditions, configv1alpha1.ConditionTypeReady))
+		assert.True(t, meta.IsStatusConditionTrue(identity.Status.Conditions, configv1alpha1.ConditionTypeConflict))
+		assert.Equal(t, "Warning StrategiesDisagree "+expectedMessage, <-recorder.Events)
+	})
+
+	t.Run("use cluster name of the quorum and report outvoted strategies", func(t *testing.T) {
+		node := nodeWithConflictingClusterNames(clusterName, "other")
+		node.Labels["kops.k8s.io/instancegroup"] = "nodes"
+		node.Labels["KubernetesCluster"] = clusterName
+		reconciler, client := setupClusterIdentityReconciler(t, clusterIdentity(), []client.Object{node})
+		clusterNameFinder, err := operator.NewClusterNameFinder(operator.ClusterNameFinderOptions{
+			Strategies: []string{"nodeLabel", "capi", "kops"},
+			Quorum:     2,
+		})
+		require.NoError(t, err)
+		reconciler.ClusterNameFinder = clusterNameFinder
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+
+		_, err = reconciler.Reconcile(context.Background(), request)
+
+		assert.NoError(t, err)
+
+		expectedMessage := fmt.Sprintf("strategies disagree on the cluster name: '%s' (nodeLabel, kops) reached the quorum and outvoted 'other' (capi)", clusterName)
+		identity := getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, clusterName, identity.Status.ClusterName)
+		assert.Equal(t, "nodeLabel,kops", identity.Status.Strategy)
+		assert.True(t, meta.IsStatusConditionTrue(identity.Status.Conditions, configv1alpha1.ConditionTypeReady))
+		conflict := meta.FindStatusCondition(identity.Status.Conditions, configv1alpha1.ConditionTypeConflict)
+		require.NotNil(t, conflict)
+		assert.Equal(t, metav1.ConditionTrue, conflict.Status)
+		assert.Equal(t, configv1alpha1.ReasonStrategiesOutvoted, conflict.Reason)
+		assert.Equal(t, expectedMessage, conflict.Message)
+		assert.Equal(t, "Warning StrategiesOutvoted "+expectedMessage, <-recorder.Events)
+	})
+
+	t.Run("detect cluster name again when a source changes", func(t *testing.T) {
+		node := nodeWithClusterNameLabel(clusterName)
+		reconciler, client := setupClusterIdentityReconciler(t, clusterIdentity(), []client.Object{node})
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+		require.NoError(t, err)
+
+		node.Labels["clusterName"] = "other"
+		require.NoError(t, client.Update(context.Background(), node))
+		requests := reconciler.sourceChanged(context.Background(), node)
+		assert.Equal(t, []ctrl.Request{request}, requests)
+
+		_, err = reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+
+		identity := getClusterIdentity(t, client, "cluster")
+		assert.Equal(t, "other", identity.Status.ClusterName)
+	})
+
+	t.Run("ignore deleted cluster identity", func(t *testing.T) {
+		reconciler, _ := setupClusterIdentityReconciler(t, clusterIdentity(), nil)
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: "other"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+	})
+
+	t.Run("create cluster identity again when deleted", func(t *testing.T) {
+		identity := clusterIdentity()
+		reconciler, client := setupClusterIdentityReconciler(t, identity, nil)
+		require.NoError(t, client.Delete(context.Background(), &identity))
+
+		result, err := reconciler.Reconcile(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+
+		getClusterIdentity(t, client, "cluster")
+	})
+
+	t.Run("create cluster identity when missing", func(t *testing.T) {
+		reconciler, client := setupClusterIdentityReconciler(t, clusterIdentity(), nil)
+		reconciler.ClusterIdentityName = "other"
+
+		err := reconciler.ensureClusterIdentity(context.Background())
+		assert.NoError(t, err)
+		err = reconciler.ensureClusterIdentity(context.Background())
+		assert.NoError(t, err)
+
+		getClusterIdentity(t, client, "other")
+	})
+}
+
+func getClusterIdentity(t *testing.T, client client.Client, name string) configv1alpha1.ClusterIdentity {
+	t.Helper()
+
+	var identity configv1alpha1.ClusterIdentity
+	err := client.Get(context.Background(), types.NamespacedName{Name: name}, &identity)
+	require.NoError(t, err)
+
+	return identity
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	configv1alpha1 "github.com/lunarway/cluster-identity-controller/apis/config/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func clusterIdentity() configv1alpha1.ClusterIdentity {
+	return configv1alpha1.ClusterIdentity{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ClusterIdentity",
+			APIVersion: configv1alpha1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster",
+		},
+	}
+}
+
+func nodeWithClusterNameLabel(clusterName string) *corev1.Node {
+	return &corev1.Node{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Node",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node",
+			Labels: map[string]string{
+				"clusterName": clusterName,
+			},
+		},
+	}
+}
+
+func nodeWithConflictingClusterNames(clusterName, capiClusterName string) *corev1.Node {
+	return &corev1.Node{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Node",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node",
+			Labels: map[string]string{
+				"clusterName":                   clusterName,
+				"cluster.x-k8s.io/cluster-name": capiClusterName,
+			},
+		},
+	}
+}
+
+func kubeSystemNamespace() *corev1.Namespace {
+	return &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "kube-system",
+			UID:  "6a9cbbb4-0b1e-4c6a-9f0e-2b7e1c3f4d5a",
+		},
+	}
+}
@@ -2,10 +2,14 @@ package core
 
 import (
 	"fmt"
+	"strings"
 
+	configv1alpha1 "github.com/lunarway/cluster-identity-controller/apis/config/v1alpha1"
 	"github.com/lunarway/cluster-identity-controller/internal/operator"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func corednsAutoscalerPod(clusterName string) corev1.Pod {
@@ -127,6 +131,251 @@ func nodeWithClusterNameLabel(clusterName string) corev1.Node {
 	}
 }
 
+func eksctlNode(clusterName string) corev1.Node {
+	return corev1.Node{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Node",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "ip-192-168-12-34.eu-west-1.compute.internal",
+			Labels: map[string]string{
+				"alpha.eksctl.io/cluster-name":   clusterName,
+				"alpha.eksctl.io/nodegroup-name": "ng-1",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID: "aws:///eu-west-1a/i-0123456789abcdef0",
+		},
+	}
+}
+
+func eksManagedNode() corev1.Node {
+	return corev1.Node{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Node",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "ip-192-168-56-78.eu-west-1.compute.internal",
+			Labels: map[string]string{
+				"eks.amazonaws.com/nodegroup":   "managed-ng",
+				"topology.kubernetes.io/region": "eu-west-1",
+				"topology.kubernetes.io/zone":   "eu-west-1b",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID: "aws:///eu-west-1b/i-0fedcba9876543210",
+		},
+	}
+}
+
+func awsNodeDaemonSet(clusterName string) appsv1.DaemonSet {
+	return appsv1.DaemonSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "DaemonSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "aws-node",
+			Namespace: "kube-system",
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "aws-node",
+							Image: "602401143452.dkr.ecr.eu-west-1.amazonaws.com/amazon-k8s-cni:v1.12.6",
+							Env: []corev1.EnvVar{
+								{
+									Name:  "AWS_VPC_K8S_CNI_LOGLEVEL",
+									Value: "DEBUG",
+								},
+								{
+									Name:  "CLUSTER_NAME",
+									Value: clusterName,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func gkeNode() corev1.Node {
+	return corev1.Node{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Node",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "gke-prod-weu-default-pool-4b7f3c2a-x8kd",
+			Labels: map[string]string{
+				"cloud.google.com/gke-nodepool":        "default-pool",
+				"cloud.google.com/gke-os-distribution": "cos",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID: "gce://lunar-prod/europe-west1-b/gke-prod-weu-default-pool-4b7f3c2a-x8kd",
+		},
+	}
+}
+
+func aksNode(nodeResourceGroup string) corev1.Node {
+	return corev1.Node{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Node",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "aks-nodepool1-12345678-vmss000000",
+			Labels: map[string]string{
+				"kubernetes.azure.com/agentpool": "nodepool1",
+				"kubernetes.azure.com/cluster":   nodeResourceGroup,
+			},
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID: "azure:///subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/" + nodeResourceGroup + "/providers/Microsoft.Compute/virtualMachineScaleSets/aks-nodepool1-12345678-vmss/virtualMachines/0",
+		},
+	}
+}
+
+func kubeadmConfigConfigMap(clusterName string) corev1.ConfigMap {
+	return corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kubeadm-config",
+			Namespace: "kube-system",
+		},
+		Data: map[string]string{
+			"ClusterConfiguration": fmt.Sprintf(`apiServer:
+  extraArgs:
+    authorization-mode: Node,RBAC
+  timeoutForControlPlane: 4m0s
+apiVersion: kubeadm.k8s.io/v1beta3
+certificatesDir: /etc/kubernetes/pki
+clusterName: %s
+controlPlaneEndpoint: k8s-api.lunar.tech:6443
+controllerManager: {}
+dns: {}
+etcd:
+  local:
+    dataDir: /var/lib/etcd
+imageRepository: registry.k8s.io
+kind: ClusterConfiguration
+kubernetesVersion: v1.27.2
+networking:
+  dnsDomain: cluster.local
+  podSubnet: 10.244.0.0/16
+  serviceSubnet: 10.96.0.0/12
+scheduler: {}
+`, clusterName),
+		},
+	}
+}
+
+func kopsNode(labels map[string]string) corev1.Node {
+	nodeLabels := map[string]string{
+		"kops.k8s.io/instancegroup":     "nodes-eu-west-1a",
+		"node-role.kubernetes.io/node":  "",
+		"topology.kubernetes.io/region": "eu-west-1",
+		"topology.kubernetes.io/zone":   "eu-west-1a",
+	}
+	for key, value := range labels {
+		nodeLabels[key] = value
+	}
+	return corev1.Node{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Node",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "i-0123456789abcdef0",
+			Labels: nodeLabels,
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID: "aws:///eu-west-1a/i-0123456789abcdef0",
+		},
+	}
+}
+
+func clusterAutoscalerDeployment(clusterName string) appsv1.Deployment {
+	return appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-autoscaler",
+			Namespace: "kube-system",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "cluster-autoscaler",
+							Image: "registry.k8s.io/autoscaling/cluster-autoscaler:v1.27.2",
+							Command: []string{
+								"./cluster-autoscaler",
+							},
+							Args: []string{
+								"--balance-similar-node-groups=false",
+								"--cloud-provider=aws",
+								"--expander=random",
+								fmt.Sprintf("--node-group-auto-discovery=asg:tag=k8s.io/cluster-autoscaler/enabled,k8s.io/cluster-autoscaler/%s", clusterName),
+								"--scale-down-enabled=true",
+								"--v=2",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func openShiftInfrastructure(clusterName string) unstructured.Unstructured {
+	return unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "config.openshift.io/v1",
+			"kind":       "Infrastructure",
+			"metadata": map[string]interface{}{
+				"name": "cluster",
+			},
+			"spec": map[string]interface{}{
+				"cloudConfig": map[string]interface{}{
+					"name": "",
+				},
+				"platformSpec": map[string]interface{}{
+					"type": "AWS",
+				},
+			},
+			"status": map[string]interface{}{
+				"apiServerInternalURI":   fmt.Sprintf("https://api-int.%s.openshift.lunar.tech:6443", clusterName),
+				"apiServerURL":           fmt.Sprintf("https://api.%s.openshift.lunar.tech:6443", clusterName),
+				"controlPlaneTopology":   "HighlyAvailable",
+				"etcdDiscoveryDomain":    "",
+				"infrastructureName":     clusterName + "-x7k2p",
+				"infrastructureTopology": "HighlyAvailable",
+				"platform":               "AWS",
+				"platformStatus": map[string]interface{}{
+					"aws": map[string]interface{}{
+						"region": "eu-west-1",
+					},
+					"type": "AWS",
+				},
+			},
+		},
+	}
+}
+
 func injectableNamespace() corev1.Namespace {
 	return corev1.Namespace{
 		TypeMeta: metav1.TypeMeta{
@@ -142,6 +391,19 @@ func injectableNamespace() corev1.Namespace {
 	}
 }
 
+func kubeSystemNamespace() corev1.Namespace {
+	return corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Namespace",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "kube-system",
+			UID:  "6a9cbbb4-0b1e-4c6a-9f0e-2b7e1c3f4d5a",
+		},
+	}
+}
+
 func nonInjectableNamespace() corev1.Namespace {
 	return corev1.Namespace{
 		TypeMeta: metav1.TypeMeta{
@@ -171,3 +433,60 @@ func clusterIdentityConfigMap(namespace, name string) corev1.ConfigMap {
 		},
 	}
 }
+
+func managedConfigMap(namespace, name string) corev1.ConfigMap {
+	configMap := clusterIdentityConfigMap(namespace, name)
+	configMap.Labels = map[string]string{
+		operator.ManagedByLabel: operator.ManagedByValue,
+	}
+	return configMap
+}
+
+// dataManagedFields returns managed fields where the manager owns the data
+// keys.
+func dataManagedFields(manager string, operation metav1.ManagedFieldsOperationType, keys ...string) []metav1.ManagedFieldsEntry {
+	fields := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, fmt.Sprintf(`"f:%s":{}`, key))
+	}
+	return []metav1.ManagedFieldsEntry{
+		{
+			Manager:    manager,
+			Operation:  operation,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(fmt.Sprintf(`{"f:data":{%s}}`, strings.Join(fields, ",")))},
+		},
+	}
+}
+
+func managedSecret(namespace, name string) corev1.Secret {
+	return corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				operator.ManagedByLabel: operator.ManagedByValue,
+			},
+		},
+		Data: map[string][]byte{
+			"clusterName": []byte("old"),
+		},
+	}
+}
+
+func clusterIdentity() configv1alpha1.ClusterIdentity {
+	return configv1alpha1.ClusterIdentity{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ClusterIdentity",
+			APIVersion: configv1alpha1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster",
+		},
+	}
+}
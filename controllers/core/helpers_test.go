@@ -12,6 +12,27 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// serverSideApply makes the fake client create objects on server-side apply
+// like the API server does. Applying to existing objects is handled by the
+// fake client as a strategic merge patch, so keys are never removed and field
+// ownership is not tracked. Those guarantees are tested against an API server
+// in TestCreateOrUpdateConfigMap in the operator package.
+func serverSideApply(ctx context.Context, apiClient client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return apiClient.Patch(ctx, obj, patch, opts...)
+	}
+
+	existing := obj.DeepCopyObject().(client.Object)
+	err := apiClient.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if apierrors.IsNotFound(err) {
+		return apiClient.Create(ctx, obj)
+	}
+	if err != nil {
+		return err
+	}
+	return apiClient.Patch(ctx, obj, patch, opts...)
+}
+
 func checkNamespacesForConfigMap(t *testing.T, client client.Client, injectedNamespace, configMapKey string, configMapData map[string]string) {
 	t.Helper()
 
@@ -58,3 +79,16 @@ func namespaceHasConfigMap(t *testing.T, client client.Client, namespace, config
 
 	return *fetched, true
 }
+
+func getSecret(t *testing.T, client client.Client, namespace, name string) corev1.Secret {
+	t.Helper()
+
+	var secret corev1.Secret
+	err := client.Get(context.Background(), types.NamespacedName{
+		Namespace: namespace,
+		Name:      name,
+	}, &secret)
+	require.NoError(t, err)
+
+	return secret
+}
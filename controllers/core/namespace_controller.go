@@ -18,28 +18,72 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"time"
 
+	configv1alpha1 "github.com/lunarway/cluster-identity-controller/apis/config/v1alpha1"
 	"github.com/lunarway/cluster-identity-controller/internal/operator"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+const (
+	reasonInvalidTemplate      = "InvalidTemplate"
+	reasonTemplateRenderFailed = "TemplateRenderFailed"
+	reasonNotManaged           = "NotManaged"
+	reasonInvalidAnnotation    = "InvalidAnnotation"
+)
+
+// errNotManaged is returned when a ConfigMap or Secret with the name of the
+// managed one exists but was not created by the operator.
+var errNotManaged = errors.New("not managed by the operator")
+
 // NamespaceReconciler reconciles a Namespace object
 type NamespaceReconciler struct {
 	client.Client
-	ConfigMapKey      string
-	ClusterNameFinder *operator.ClusterNameFinder
+	// APIReader reads ConfigMaps and Secrets that are not in the cache of
+	// Client, which only holds managed ones.
+	APIReader    client.Reader
+	ConfigMapKey string
+	// ClusterIdentityName is the name of the ClusterIdentity object the cluster
+	// name is copied from.
+	ClusterIdentityName string
+	// Sink is where the identity is written. The zero value writes a
+	// ConfigMap. Secrets are only watched and deleted when the sink includes
+	// Secrets as access to Secrets is opt-in, see
+	// config/components/secret-sink.
+	Sink operator.Sink
+	// KeepConfigMaps keeps the managed ConfigMaps and Secrets when a namespace
+	// opts out of injection instead of deleting them.
+	KeepConfigMaps bool
+	// NamespaceFilter decides which namespaces are injected.
+	NamespaceFilter operator.NamespaceFilter
+	// Templates render additional ConfigMap keys from the identity.
+	Templates operator.Templates
+	Recorder  record.EventRecorder
+	// MigrationGracePeriod is how long managed ConfigMaps with another name
+	// than ConfigMapKey are kept after their data is moved to ConfigMapKey.
+	MigrationGracePeriod time.Duration
 }
 
-//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
-//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.lunar.tech,resources=clusteridentities,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
-//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -56,33 +100,366 @@ func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
-	isInjectable := operator.IsNamespaceInjectable(namespace)
+	isInjectable := r.NamespaceFilter.IsNamespaceInjectable(namespace)
 	if !isInjectable {
-		logger.Info("namespace is not injectable. Skipping.")
+		if r.KeepConfigMaps {
+			logger.Info("namespace is not injectable. Skipping.")
+			return ctrl.Result{}, nil
+		}
+		logger.Info("namespace is not injectable. Removing managed ConfigMaps and Secrets.")
+		err := operator.DeleteManagedConfigMaps(ctx, r.Client, req.Name)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if r.Sink.Secret() {
+			err = operator.DeleteManagedSecrets(ctx, r.Client, req.Name)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
 		return ctrl.Result{}, nil
 	}
 
-	clusterName, err := r.ClusterNameFinder.GetClusterName(ctx, r.Client)
+	output, err := operator.OutputForNamespace(namespace, r.ConfigMapKey)
 	if err != nil {
-		return ctrl.Result{}, err
+		r.Recorder.Event(&namespace, corev1.EventTypeWarning, reasonInvalidAnnotation, err.Error())
+		return ctrl.Result{}, fmt.Errorf("namespace '%s': %w", namespace.Name, err)
+	}
+	templates, err := r.Templates.TemplatesForNamespace(namespace)
+	if err != nil {
+		r.Recorder.Event(&namespace, corev1.EventTypeWarning, reasonInvalidTemplate, err.Error())
+		return ctrl.Result{}, fmt.Errorf("namespace '%s': %w", namespace.Name, err)
+	}
+
+	var identity configv1alpha1.ClusterIdentity
+	err = r.Client.Get(ctx, types.NamespacedName{Name: r.ClusterIdentityName}, &identity)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("get cluster identity '%s': %w", r.ClusterIdentityName, err)
 	}
 
-	err = operator.CreateOrUpdateConfigMap(ctx, r.Client, types.NamespacedName{
+	ready := meta.FindStatusCondition(identity.Status.Conditions, configv1alpha1.ConditionTypeReady)
+	if ready == nil || ready.Status != metav1.ConditionTrue {
+		return ctrl.Result{}, fmt.Errorf("cluster identity '%s' is not ready: %s", r.ClusterIdentityName, conditionMessage(ready))
+	}
+	clusterName := identity.Status.ClusterName
+	nn := types.NamespacedName{
 		Namespace: req.Name,
-		Name:      r.ConfigMapKey,
-	}, clusterName)
+		Name:      output.ConfigMapName,
+	}
+	clusterIdentity := identityFromStatus(identity.Status)
+	data, err := output.Data(clusterIdentity.Data())
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("store cluster clusterName '%s' in configmap: %w", clusterName, err)
+		// the keys are only invalid because of the key prefix and mapping
+		err = fmt.Errorf("invalid annotations '%s' and '%s': %w", operator.KeyPrefixAnnotation, operator.KeyMappingAnnotation, err)
+		r.Recorder.Event(&namespace, corev1.EventTypeWarning, reasonInvalidAnnotation, err.Error())
+		return ctrl.Result{}, fmt.Errorf("namespace '%s': %w", namespace.Name, err)
+	}
+	rendered, err := templates.Render(clusterIdentity)
+	if err != nil {
+		r.Recorder.Event(&namespace, corev1.EventTypeWarning, reasonTemplateRenderFailed, err.Error())
+		return ctrl.Result{}, fmt.Errorf("namespace '%s': %w", namespace.Name, err)
+	}
+	// rendered keys are written as is and take precedence over the identity
+	for key, value := range rendered {
+		data[key] = value
+	}
+
+	conflict := meta.FindStatusCondition(identity.Status.Conditions, configv1alpha1.ConditionTypeConflict)
+	if conflict != nil && conflict.Status == metav1.ConditionTrue && conflict.Reason == configv1alpha1.ReasonStrategiesDisagree {
+		kind, err := r.existingSink(ctx, nn)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if kind != "" {
+			// the namespace is reconciled again when the conflict is resolved
+			message := fmt.Sprintf("cluster identity '%s' has a conflict, not overwriting %s '%s': %s", r.ClusterIdentityName, kind, nn, conflict.Message)
+			logger.Info(message)
+			r.Recorder.Event(&namespace, corev1.EventTypeWarning, configv1alpha1.ReasonStrategiesDisagree, message)
+			return ctrl.Result{}, nil
+		}
+	}
+
+	err = r.checkManaged(ctx, nn)
+	if errors.Is(err, errNotManaged) {
+		// pointing the annotation at an existing ConfigMap would otherwise
+		// take it over and delete it once the annotation is removed again
+		if _, ok := namespace.Annotations[operator.ConfigMapNameAnnotation]; ok {
+			err = fmt.Errorf("invalid annotation '%s': %w", operator.ConfigMapNameAnnotation, err)
+			r.Recorder.Event(&namespace, corev1.EventTypeWarning, reasonInvalidAnnotation, err.Error())
+		} else {
+			r.Recorder.Event(&namespace, corev1.EventTypeWarning, reasonNotManaged, err.Error())
+		}
+	}
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("namespace '%s': %w", namespace.Name, err)
+	}
+
+	var requeueAfter time.Duration
+	if r.Sink.ConfigMap() {
+		err = operator.CreateOrUpdateConfigMap(ctx, r.Client, nn, data, configMapSource(&identity))
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("store cluster clusterName '%s' in configmap: %w", clusterName, err)
+		}
+
+		requeueAfter, err = operator.MigrateConfigMaps(ctx, r.Client, nn, r.MigrationGracePeriod, time.Now())
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("migrate renamed ConfigMaps: %w", err)
+		}
+	} else {
+		err = operator.DeleteManagedConfigMaps(ctx, r.Client, req.Name)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if r.Sink.Secret() {
+		err = operator.CreateOrUpdateSecret(ctx, r.Client, nn, data, configMapSource(&identity))
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("store cluster clusterName '%s' in secret: %w", clusterName, err)
+		}
+
+		secretRequeueAfter, err := operator.DeleteRenamedSecrets(ctx, r.Client, nn, r.MigrationGracePeriod, time.Now())
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("delete renamed Secrets: %w", err)
+		}
+		if requeueAfter == 0 || (secretRequeueAfter > 0 && secretRequeueAfter < requeueAfter) {
+			requeueAfter = secretRequeueAfter
+		}
 	}
 
 	logger.Info("Completed reconciliation of namespace")
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// existingSink returns the kind of the first existing ConfigMap or Secret of
+// the enabled sinks. An empty string is returned if none of them exist.
+func (r *NamespaceReconciler) existingSink(ctx context.Context, nn types.NamespacedName) (string, error) {
+	if r.Sink.ConfigMap() {
+		exists, err := objectExists(ctx, r.Client, nn, &corev1.ConfigMap{})
+		if err != nil {
+			return "", fmt.Errorf("get ConfigMap '%s': %w", nn, err)
+		}
+		if exists {
+			return "ConfigMap", nil
+		}
+	}
+	if r.Sink.Secret() {
+		exists, err := objectExists(ctx, r.Client, nn, &corev1.Secret{})
+		if err != nil {
+			return "", fmt.Errorf("get Secret '%s': %w", nn, err)
+		}
+		if exists {
+			return "Secret", nil
+		}
+	}
+	return "", nil
+}
+
+// checkManaged returns an error wrapping errNotManaged if a ConfigMap or Secret
+// of the enabled sinks exists with the name of nn without being managed by the
+// operator. Applying to it would label it as managed so it would later be
+// deleted.
+func (r *NamespaceReconciler) checkManaged(ctx context.Context, nn types.NamespacedName) error {
+	if r.Sink.ConfigMap() {
+		err := r.checkObjectManaged(ctx, "ConfigMap", nn, &corev1.ConfigMap{})
+		if err != nil {
+			return err
+		}
+	}
+	if r.Sink.Secret() {
+		err := r.checkObjectManaged(ctx, "Secret", nn, &corev1.Secret{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkObjectManaged checks a single object, see checkManaged. The cache only
+// holds managed objects so the API server is only asked when the object is
+// not in the cache.
+func (r *NamespaceReconciler) checkObjectManaged(ctx context.Context, kind string, nn types.NamespacedName, obj client.Object) error {
+	err := r.Client.Get(ctx, nn, obj)
+	if err == nil && operator.IsManaged(obj) {
+		return nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("get %s '%s': %w", kind, nn, err)
+	}
+
+	exists, err := objectExists(ctx, r.APIReader, nn, obj)
+	if err != nil {
+		return fmt.Errorf("get %s '%s': %w", kind, nn, err)
+	}
+	if exists && !operator.IsManaged(obj) {
+		return fmt.Errorf("%s '%s' exists and is %w", kind, nn, errNotManaged)
+	}
+	return nil
+}
+
+func objectExists(ctx context.Context, apiClient client.Reader, nn types.NamespacedName, obj client.Object) (bool, error) {
+	err := apiClient.Get(ctx, nn, obj)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// configMapSource returns the source recorded on the ConfigMaps and Secrets
+// written from the ClusterIdentity.
+func configMapSource(identity *configv1alpha1.ClusterIdentity) operator.ConfigMapSource {
+	source := operator.ConfigMapSource{
+		Strategy: identity.Status.Strategy,
+	}
+	if identity.Status.LastObservedTime != nil {
+		source.DetectionTime = identity.Status.LastObservedTime.Time
+	}
+	return source
+}
+
+// identityFromStatus returns the identity recorded in the status of a
+// ClusterIdentity.
+func identityFromStatus(status configv1alpha1.ClusterIdentityStatus) operator.Identity {
+	return operator.Identity{
+		ClusterName:       status.ClusterName,
+		ClusterID:         status.ClusterID,
+		CloudProvider:     status.CloudProvider,
+		Region:            status.Region,
+		Zones:             status.Zones,
+		Account:           status.Account,
+		Project:           status.Project,
+		Environment:       status.Environment,
+		KubernetesVersion: status.KubernetesVersion,
+		Attributes:        status.Attributes,
+	}
+}
+
+func conditionMessage(condition *metav1.Condition) string {
+	if condition == nil {
+		return "cluster name not detected yet"
+	}
+	return condition.Message
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *NamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Namespace{}).
-		Complete(r)
+	managed := predicate.NewPredicateFuncs(operator.IsManaged)
+
+	controller := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Namespace{}, builder.WithPredicates(injectablePredicate{filter: r.NamespaceFilter})).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(configMapNamespace), builder.WithPredicates(managed)).
+		Watches(&configv1alpha1.ClusterIdentity{}, handler.EnqueueRequestsFromMapFunc(r.injectableNamespaces), builder.WithPredicates(
+			predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return obj.GetName() == r.ClusterIdentityName
+			}),
+			identityChangedPredicate{},
+		))
+	if r.Sink.Secret() {
+		controller = controller.Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(configMapNamespace), builder.WithPredicates(managed))
+	}
+	return controller.Complete(r)
+}
+
+// configMapNamespace returns a request for the namespace of a managed ConfigMap
+// or Secret so changes to it are reverted.
+func configMapNamespace(_ context.Context, obj client.Object) []reconcile.Request {
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: obj.GetNamespace()}},
+	}
+}
+
+// injectableNamespaces returns a request for every injectable namespace so
+// their ConfigMaps are updated when the cluster identity changes.
+func (r *NamespaceReconciler) injectableNamespaces(ctx context.Context, _ client.Object) []reconcile.Request {
+	var namespaceList corev1.NamespaceList
+	err := r.Client.List(ctx, &namespaceList)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to list namespaces for changed cluster identity")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, namespace := range namespaceList.Items {
+		if !r.NamespaceFilter.IsNamespaceInjectable(namespace) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: namespace.Name},
+		})
+	}
+	return requests
+}
+
+// injectablePredicate only passes events of injectable namespaces. Updates are
+// also passed if the namespace was injectable before so the managed ConfigMap
+// is removed when a namespace opts out.
+type injectablePredicate struct {
+	filter operator.NamespaceFilter
+}
+
+func (p injectablePredicate) Create(e event.CreateEvent) bool {
+	return p.injectable(e.Object)
+}
+
+func (p injectablePredicate) Delete(event.DeleteEvent) bool {
+	return false
+}
+
+func (p injectablePredicate) Update(e event.UpdateEvent) bool {
+	return p.injectable(e.ObjectOld) || p.injectable(e.ObjectNew)
+}
+
+func (p injectablePredicate) Generic(e event.GenericEvent) bool {
+	return p.injectable(e.Object)
+}
+
+func (p injectablePredicate) injectable(obj client.Object) bool {
+	namespace, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return false
+	}
+	return p.filter.IsNamespaceInjectable(*namespace)
+}
+
+// identityChangedPredicate only passes updates of a ClusterIdentity that change
+// the identity written to the ConfigMaps or the conditions the namespace
+// reconciler acts on. Status updates that only bump the last observed time are
+// filtered out.
+type identityChangedPredicate struct {
+	predicate.Funcs
+}
+
+func (identityChangedPredicate) Update(e event.UpdateEvent) bool {
+	oldIdentity, ok := e.ObjectOld.(*configv1alpha1.ClusterIdentity)
+	if !ok {
+		return false
+	}
+	newIdentity, ok := e.ObjectNew.(*configv1alpha1.ClusterIdentity)
+	if !ok {
+		return false
+	}
+
+	if !reflect.DeepEqual(identityFromStatus(oldIdentity.Status).Data(), identityFromStatus(newIdentity.Status).Data()) {
+		return true
+	}
+	for _, conditionType := range []string{configv1alpha1.ConditionTypeReady, configv1alpha1.ConditionTypeConflict} {
+		if conditionState(oldIdentity.Status.Conditions, conditionType) != conditionState(newIdentity.Status.Conditions, conditionType) {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionState returns the status and reason of the condition. The reason
+// is included as the Conflict condition only blocks updates with some reasons.
+func conditionState(conditions []metav1.Condition, conditionType string) string {
+	condition := meta.FindStatusCondition(conditions, conditionType)
+	if condition == nil {
+		return string(metav1.ConditionUnknown)
+	}
+	return string(condition.Status) + "/" + condition.Reason
 }
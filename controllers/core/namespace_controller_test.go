@@ -4,15 +4,26 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
+	configv1alpha1 "github.com/lunarway/cluster-identity-controller/apis/config/v1alpha1"
+	configcontrollers "github.com/lunarway/cluster-identity-controller/controllers/config"
 	"github.com/lunarway/cluster-identity-controller/internal/operator"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
@@ -20,6 +31,12 @@ import (
 func setupNamespaceReconciler(t *testing.T, configMapKey string, objects []client.Object) (*NamespaceReconciler, client.Client) {
 	t.Helper()
 
+	return setupNamespaceReconcilerWithIdentity(t, configMapKey, clusterIdentity(), objects)
+}
+
+func setupNamespaceReconcilerWithIdentity(t *testing.T, configMapKey string, identity configv1alpha1.ClusterIdentity, objects []client.Object) (*NamespaceReconciler, client.Client) {
+	t.Helper()
+
 	s := scheme.Scheme
 	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.Namespace{})
 	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.NamespaceList{})
@@ -27,20 +44,48 @@ func setupNamespaceReconciler(t *testing.T, configMapKey string, objects []clien
 	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.PodList{})
 	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.Node{})
 	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.NodeList{})
+	utilruntime.Must(configv1alpha1.AddToScheme(s))
 
 	client := fake.NewClientBuilder().
 		WithObjects(objects...).
+		WithObjects(&identity).
+		WithStatusSubresource(&identity).
+		WithInterceptorFuncs(interceptor.Funcs{Patch: serverSideApply}).
 		Build()
 
+	detectClusterIdentity(t, client, identity.Name)
+
 	reconciler := &NamespaceReconciler{
-		Client:            client,
-		ConfigMapKey:      configMapKey,
-		ClusterNameFinder: operator.NewClusterNameFinder(),
+		Client:              client,
+		APIReader:           client,
+		ConfigMapKey:        configMapKey,
+		ClusterIdentityName: identity.Name,
+		Recorder:            record.NewFakeRecorder(10),
 	}
 
 	return reconciler, client
 }
 
+// detectClusterIdentity runs the ClusterIdentity reconciler once so the
+// namespace reconciler has a cluster identity to copy from. Detection errors
+// are ignored as they are reported on the ClusterIdentity status.
+func detectClusterIdentity(t *testing.T, client client.Client, name string) {
+	t.Helper()
+
+	clusterNameFinder, err := operator.NewClusterNameFinder(operator.ClusterNameFinderOptions{})
+	require.NoError(t, err)
+
+	reconciler := &configcontrollers.ClusterIdentityReconciler{
+		Client:              client,
+		ClusterIdentityName: name,
+		ClusterNameFinder:   clusterNameFinder,
+		Recorder:            record.NewFakeRecorder(10),
+	}
+	_, _ = reconciler.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: name},
+	})
+}
+
 func TestNamespaceController(t *testing.T) {
 	logf.SetLogger(zap.New(zap.WriteTo(os.Stdout), zap.UseDevMode(true)))
 	var (
@@ -50,9 +95,20 @@ func TestNamespaceController(t *testing.T) {
 		controllerManagerPod     = kubeControllerManagerPod(clusterName)
 		coreDnsAutoScalerPod     = corednsAutoscalerPod(clusterName)
 		nodeWithClusterNameLabel = nodeWithClusterNameLabel(clusterName)
+		eksctlNode               = eksctlNode(clusterName)
+		eksManagedNode           = eksManagedNode()
+		awsNodeDaemonSet         = awsNodeDaemonSet(clusterName)
+		gkeNode                  = gkeNode()
+		aksNode                  = aksNode("MC_lunar-prod_prod-weu-01_westeurope")
+		kubeadmConfigConfigMap   = kubeadmConfigConfigMap(clusterName)
+		kopsTaggedNode           = kopsNode(map[string]string{"KubernetesCluster": clusterName})
+		kopsNode                 = kopsNode(nil)
+		clusterAutoscaler        = clusterAutoscalerDeployment(clusterName)
+		openShiftInfrastructure  = openShiftInfrastructure("prod")
 		injectableNamespace      = injectableNamespace()
 		nonInjectableNamespace   = nonInjectableNamespace()
-		clusterIdentityConfigMap = clusterIdentityConfigMap(injectableNamespace.Name, configMapKey)
+		kubeSystemNamespace      = kubeSystemNamespace()
+		clusterIdentityConfigMap = managedConfigMap(injectableNamespace.Name, configMapKey)
 	)
 
 	t.Run("update injectable namespaces via nodeLabel", func(t *testing.T) {
@@ -78,6 +134,31 @@ func TestNamespaceController(t *testing.T) {
 		})
 	})
 
+	t.Run("write kube-system namespace UID as cluster ID", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&kubeSystemNamespace,
+			&nodeWithClusterNameLabel,
+			&clusterIdentityConfigMap,
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"otherField":  "other",
+			"clusterName": clusterName,
+			"clusterID":   string(kubeSystemNamespace.UID),
+		})
+	})
+
 	t.Run("update injectable namespaces via CoreDnsAutoScalerPod", func(t *testing.T) {
 		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
 			&injectableNamespace,
@@ -96,8 +177,223 @@ func TestNamespaceController(t *testing.T) {
 		assert.Equal(t, ctrl.Result{}, result)
 
 		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
-			"otherField":  "other",
-			"clusterName": "clustername",
+			"otherField":    "other",
+			"clusterName":   "clustername-dns",
+			"cloudProvider": "azure",
+			"region":        "westeurope",
+		})
+	})
+
+	t.Run("update injectable namespaces via AKS node resource group", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&aksNode,
+			&coreDnsAutoScalerPod,
+			&clusterIdentityConfigMap,
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"otherField":    "other",
+			"clusterName":   "prod-weu-01",
+			"cloudProvider": "azure",
+			"account":       "00000000-0000-0000-0000-000000000000",
+			"resourceGroup": "lunar-prod",
+			"region":        "westeurope",
+		})
+	})
+
+	t.Run("update injectable namespaces via eksctl node labels", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&eksctlNode,
+			&clusterIdentityConfigMap,
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"otherField":    "other",
+			"clusterName":   clusterName,
+			"cloudProvider": "aws",
+			"region":        "eu-west-1",
+		})
+	})
+
+	t.Run("update injectable namespaces via aws-node DaemonSet", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&eksManagedNode,
+			&awsNodeDaemonSet,
+			&clusterIdentityConfigMap,
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"otherField":    "other",
+			"clusterName":   clusterName,
+			"cloudProvider": "aws",
+			"region":        "eu-west-1",
+			"zones":         "eu-west-1b",
+		})
+	})
+
+	t.Run("update injectable namespaces via GKE node", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&gkeNode,
+			&clusterIdentityConfigMap,
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"otherField":    "other",
+			"clusterName":   "prod-weu",
+			"cloudProvider": "gcp",
+			"project":       "lunar-prod",
+			"region":        "europe-west1",
+			"zones":         "europe-west1-b",
+		})
+	})
+
+	t.Run("update injectable namespaces via kubeadm-config ConfigMap", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&kubeadmConfigConfigMap,
+			&clusterIdentityConfigMap,
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"otherField":           "other",
+			"clusterName":          clusterName,
+			"kubernetesVersion":    "v1.27.2",
+			"controlPlaneEndpoint": "k8s-api.lunar.tech:6443",
+		})
+	})
+
+	t.Run("update injectable namespaces via kOps node tag", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&kopsTaggedNode,
+			&clusterIdentityConfigMap,
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"otherField":    "other",
+			"clusterName":   clusterName,
+			"cloudProvider": "aws",
+			"region":        "eu-west-1",
+			"zones":         "eu-west-1a",
+		})
+	})
+
+	t.Run("update injectable namespaces via kOps cluster-autoscaler", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&kopsNode,
+			&clusterAutoscaler,
+			&clusterIdentityConfigMap,
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"otherField":    "other",
+			"clusterName":   clusterName,
+			"cloudProvider": "aws",
+			"region":        "eu-west-1",
+			"zones":         "eu-west-1a",
+		})
+	})
+
+	t.Run("update injectable namespaces via OpenShift Infrastructure", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&openShiftInfrastructure,
+			&clusterIdentityConfigMap,
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"otherField":         "other",
+			"clusterName":        "prod",
+			"cloudProvider":      "aws",
+			"region":             "eu-west-1",
+			"platform":           "AWS",
+			"infrastructureName": "prod-x7k2p",
+			"apiServerURL":       "https://api.prod.openshift.lunar.tech:6443",
 		})
 	})
 
@@ -118,6 +414,80 @@ func TestNamespaceController(t *testing.T) {
 		assert.Equal(t, ctrl.Result{}, result)
 	})
 
+	t.Run("delete managed ConfigMap when namespace opts out", func(t *testing.T) {
+		configMap := managedConfigMap(nonInjectableNamespace.Name, configMapKey)
+		delete(configMap.Data, "otherField")
+		configMap.ManagedFields = dataManagedFields(string(operator.FieldOwner), metav1.ManagedFieldsOperationApply, "clusterName")
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&nonInjectableNamespace,
+			&configMap,
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: nonInjectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		_, hasConfigMap := namespaceHasConfigMap(t, client, nonInjectableNamespace.Name, configMapKey, nil)
+		assert.False(t, hasConfigMap)
+	})
+
+	t.Run("keep keys of others in managed ConfigMap when namespace opts out", func(t *testing.T) {
+		configMap := managedConfigMap(nonInjectableNamespace.Name, configMapKey)
+		configMap.ManagedFields = append(
+			dataManagedFields(string(operator.FieldOwner), metav1.ManagedFieldsOperationApply, "clusterName"),
+			dataManagedFields("kubectl-edit", metav1.ManagedFieldsOperationUpdate, "otherField")...,
+		)
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&nonInjectableNamespace,
+			&configMap,
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: nonInjectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		kept, hasConfigMap := namespaceHasConfigMap(t, client, nonInjectableNamespace.Name, configMapKey, nil)
+		require.True(t, hasConfigMap)
+		assert.Equal(t, "other", kept.Data["otherField"])
+	})
+
+	t.Run("keep ConfigMap not managed by the operator when namespace opts out", func(t *testing.T) {
+		configMap := clusterIdentityConfigMap.DeepCopy()
+		configMap.Namespace = nonInjectableNamespace.Name
+		configMap.Labels = nil
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&nonInjectableNamespace,
+			configMap,
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: nonInjectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		_, hasConfigMap := namespaceHasConfigMap(t, client, nonInjectableNamespace.Name, configMapKey, nil)
+		assert.True(t, hasConfigMap)
+	})
+
+	t.Run("keep managed ConfigMap when namespace opts out if configured", func(t *testing.T) {
+		configMap := managedConfigMap(nonInjectableNamespace.Name, configMapKey)
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&nonInjectableNamespace,
+			&configMap,
+		})
+		reconciler.KeepConfigMaps = true
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: nonInjectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		_, hasConfigMap := namespaceHasConfigMap(t, client, nonInjectableNamespace.Name, configMapKey, nil)
+		assert.True(t, hasConfigMap)
+	})
+
 	t.Run("inject to injectable namespaces", func(t *testing.T) {
 		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
 			&controllerManagerPod,
@@ -162,8 +532,13 @@ func TestNamespaceController(t *testing.T) {
 		})
 	})
 
-	t.Run("fail if cluster name cannot be detected", func(t *testing.T) {
-		reconciler, _ := setupNamespaceReconciler(t, configMapKey, []client.Object{
+	t.Run("inject pinned attributes next to the cluster name", func(t *testing.T) {
+		identity := clusterIdentity()
+		identity.Spec.Attributes = map[string]string{
+			"environment": "prod",
+		}
+		reconciler, client := setupNamespaceReconcilerWithIdentity(t, configMapKey, identity, []client.Object{
+			&controllerManagerPod,
 			&injectableNamespace,
 		})
 
@@ -173,7 +548,667 @@ func TestNamespaceController(t *testing.T) {
 				Name:      injectableNamespace.Name,
 			},
 		})
-		assert.EqualError(t, err, "could not detect cluster name")
+
+		assert.NoError(t, err)
 		assert.Equal(t, ctrl.Result{}, result)
+
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"clusterName": clusterName,
+			"environment": "prod",
+		})
+	})
+
+	t.Run("apply to ConfigMap with only binary data", func(t *testing.T) {
+		configMap := clusterIdentityConfigMap.DeepCopy()
+		configMap.Data = nil
+		configMap.BinaryData = map[string][]byte{"certificate": []byte("binary")}
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+			configMap,
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: injectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"clusterName": clusterName,
+		})
+		applied, _ := namespaceHasConfigMap(t, client, injectableNamespace.Name, configMapKey, nil)
+		assert.Equal(t, configMap.BinaryData, applied.BinaryData)
+	})
+
+	t.Run("not take over ConfigMap not managed by the operator", func(t *testing.T) {
+		configMap := clusterIdentityConfigMap.DeepCopy()
+		configMap.Labels = nil
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+			configMap,
+		})
+		recorder := record.NewFakeRecorder(1)
+		reconciler.Recorder = recorder
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: injectableNamespace.Name},
+		})
+
+		assert.EqualError(t, err, "namespace 'injectable': ConfigMap 'injectable/cluster-identity' exists and is not managed by the operator")
+		assert.Equal(t, "Warning NotManaged ConfigMap 'injectable/cluster-identity' exists and is not managed by the operator", <-recorder.Events)
+		unchanged, _ := namespaceHasConfigMap(t, client, injectableNamespace.Name, configMapKey, nil)
+		assert.Equal(t, configMap.Data, unchanged.Data)
+		assert.Empty(t, unchanged.Labels)
+	})
+
+	t.Run("record ownership on managed ConfigMap", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+			&clusterIdentityConfigMap,
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: injectableNamespace.Name},
+		})
+		require.NoError(t, err)
+
+		configMap, _ := namespaceHasConfigMap(t, client, injectableNamespace.Name, configMapKey, nil)
+		assert.Equal(t, map[string]string{operator.ManagedByLabel: operator.ManagedByValue}, configMap.Labels)
+		assert.Equal(t, "nodeLabel", configMap.Annotations[operator.StrategyAnnotation])
+		assert.NotEmpty(t, configMap.Annotations[operator.DetectionTimeAnnotation])
+		assert.Empty(t, configMap.OwnerReferences)
+	})
+
+	t.Run("revert changes to managed ConfigMap", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+		})
+		request := ctrl.Request{NamespacedName: types.NamespacedName{Name: injectableNamespace.Name}}
+		_, err := reconciler.Reconcile(context.Background(), request)
+		require.NoError(t, err)
+
+		configMap, _ := namespaceHasConfigMap(t, client, injectableNamespace.Name, configMapKey, nil)
+		assert.Equal(t, operator.ManagedByValue, configMap.Labels[operator.ManagedByLabel])
+		configMap.Data["clusterName"] = "edited"
+		require.NoError(t, client.Update(context.Background(), &configMap))
+
+		_, err = reconciler.Reconcile(context.Background(), request)
+
+		assert.NoError(t, err)
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"clusterName": clusterName,
+		})
+	})
+
+	t.Run("recreate deleted managed ConfigMap", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+		})
+		request := ctrl.Request{NamespacedName: types.NamespacedName{Name: injectableNamespace.Name}}
+		_, err := reconciler.Reconcile(context.Background(), request)
+		require.NoError(t, err)
+
+		configMap, _ := namespaceHasConfigMap(t, client, injectableNamespace.Name, configMapKey, nil)
+		require.NoError(t, client.Delete(context.Background(), &configMap))
+
+		_, err = reconciler.Reconcile(context.Background(), request)
+
+		assert.NoError(t, err)
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"clusterName": clusterName,
+		})
+	})
+
+	t.Run("inject namespace selected by label", func(t *testing.T) {
+		namespace := nonInjectableNamespace.DeepCopy()
+		namespace.Labels = map[string]string{"team": "payments"}
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			namespace,
+			&nodeWithClusterNameLabel,
+		})
+		filter, err := operator.NewNamespaceFilter("team", "kube-*")
+		require.NoError(t, err)
+		reconciler.NamespaceFilter = filter
+
+		_, err = reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: namespace.Name},
+		})
+
+		assert.NoError(t, err)
+		checkNamespacesForConfigMap(t, client, namespace.Name, configMapKey, map[string]string{
+			"clusterName": clusterName,
+		})
+	})
+
+	t.Run("write keys customized by namespace annotations", func(t *testing.T) {
+		namespace := injectableNamespace.DeepCopy()
+		namespace.Annotations[operator.ConfigMapNameAnnotation] = "cluster"
+		namespace.Annotations[operator.KeyPrefixAnnotation] = "cluster."
+		namespace.Annotations[operator.KeyMappingAnnotation] = "clusterName=CLUSTER_NAME"
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			namespace,
+			&nodeWithClusterNameLabel,
+			&kubeSystemNamespace,
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: namespace.Name},
+		})
+
+		assert.NoError(t, err)
+		checkNamespacesForConfigMap(t, client, namespace.Name, "cluster", map[string]string{
+			"CLUSTER_NAME":      clusterName,
+			"cluster.clusterID": string(kubeSystemNamespace.UID),
+		})
+	})
+
+	t.Run("fail on invalid namespace annotations", func(t *testing.T) {
+		namespace := injectableNamespace.DeepCopy()
+		namespace.Annotations[operator.KeyMappingAnnotation] = "clusterName"
+		reconciler, _ := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			namespace,
+			&nodeWithClusterNameLabel,
+		})
+		recorder := record.NewFakeRecorder(1)
+		reconciler.Recorder = recorder
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: namespace.Name},
+		})
+
+		assert.EqualError(t, err, "namespace 'injectable': invalid annotation 'config.lunar.tech/cluster-identity-key-mapping': 'clusterName' is not on the form <key>=<new key>")
+		assert.Equal(t, "Warning InvalidAnnotation invalid annotation 'config.lunar.tech/cluster-identity-key-mapping': 'clusterName' is not on the form <key>=<new key>", <-recorder.Events)
+	})
+
+	t.Run("record event when keys collide after mapping", func(t *testing.T) {
+		namespace := injectableNamespace.DeepCopy()
+		namespace.Annotations[operator.KeyMappingAnnotation] = "clusterName=name,clusterID=name"
+		reconciler, _ := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			namespace,
+			&nodeWithClusterNameLabel,
+			&kubeSystemNamespace,
+		})
+		recorder := record.NewFakeRecorder(1)
+		reconciler.Recorder = recorder
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: namespace.Name},
+		})
+
+		assert.EqualError(t, err, "namespace 'injectable': invalid annotations 'config.lunar.tech/cluster-identity-key-prefix' and 'config.lunar.tech/cluster-identity-key-mapping': more than one key is written to 'name'")
+		assert.Contains(t, <-recorder.Events, "Warning InvalidAnnotation invalid annotations")
+	})
+
+	t.Run("reject ConfigMap name annotation naming a ConfigMap not managed by the operator", func(t *testing.T) {
+		namespace := injectableNamespace.DeepCopy()
+		namespace.Annotations[operator.ConfigMapNameAnnotation] = "app-config"
+		appConfig := clusterIdentityConfigMap.DeepCopy()
+		appConfig.Name = "app-config"
+		appConfig.Labels = nil
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			namespace,
+			&nodeWithClusterNameLabel,
+			appConfig,
+		})
+		recorder := record.NewFakeRecorder(1)
+		reconciler.Recorder = recorder
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: namespace.Name},
+		})
+
+		assert.EqualError(t, err, "namespace 'injectable': invalid annotation 'config.lunar.tech/cluster-identity-configmap-name': ConfigMap 'injectable/app-config' exists and is not managed by the operator")
+		assert.Equal(t, "Warning InvalidAnnotation invalid annotation 'config.lunar.tech/cluster-identity-configmap-name': ConfigMap 'injectable/app-config' exists and is not managed by the operator", <-recorder.Events)
+		unchanged, _ := namespaceHasConfigMap(t, client, namespace.Name, "app-config", nil)
+		assert.Equal(t, appConfig.Data, unchanged.Data)
+		assert.Empty(t, unchanged.Labels)
+	})
+
+	t.Run("write rendered templates", func(t *testing.T) {
+		namespace := injectableNamespace.DeepCopy()
+		namespace.Annotations[operator.TemplatesAnnotation] = "application.properties: cluster.name={{ .ClusterName }}"
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			namespace,
+			&nodeWithClusterNameLabel,
+		})
+		templates, err := operator.ParseTemplates(map[string]string{
+			"otel.resource.attributes": "k8s.cluster.name={{ .ClusterName }}",
+		})
+		require.NoError(t, err)
+		reconciler.Templates = templates
+
+		_, err = reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: namespace.Name},
+		})
+
+		assert.NoError(t, err)
+		checkNamespacesForConfigMap(t, client, namespace.Name, configMapKey, map[string]string{
+			"clusterName":              clusterName,
+			"otel.resource.attributes": "k8s.cluster.name=" + clusterName,
+			"application.properties":   "cluster.name=" + clusterName,
+		})
+	})
+
+	t.Run("record event for invalid namespace templates", func(t *testing.T) {
+		namespace := injectableNamespace.DeepCopy()
+		namespace.Annotations[operator.TemplatesAnnotation] = "name: '{{ .Name }}'"
+		reconciler, _ := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			namespace,
+			&nodeWithClusterNameLabel,
+		})
+		recorder := record.NewFakeRecorder(1)
+		reconciler.Recorder = recorder
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: namespace.Name},
+		})
+
+		assert.ErrorContains(t, err, "namespace 'injectable': invalid annotation 'config.lunar.tech/cluster-identity-templates'")
+		assert.Contains(t, <-recorder.Events, "Warning InvalidTemplate invalid annotation 'config.lunar.tech/cluster-identity-templates'")
+	})
+
+	t.Run("record event when template cannot be rendered", func(t *testing.T) {
+		reconciler, _ := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+		})
+		templates, err := operator.ParseTemplates(map[string]string{
+			"zone": "{{ if .ClusterName }}{{ index .Zones 0 }}{{ end }}",
+		})
+		require.NoError(t, err)
+		reconciler.Templates = templates
+		recorder := record.NewFakeRecorder(1)
+		reconciler.Recorder = recorder
+
+		_, err = reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: injectableNamespace.Name},
+		})
+
+		assert.ErrorContains(t, err, "namespace 'injectable': render template 'zone'")
+		assert.Contains(t, <-recorder.Events, "Warning TemplateRenderFailed render template 'zone'")
+	})
+
+	t.Run("write Secret instead of ConfigMap", func(t *testing.T) {
+		configMap := managedConfigMap(injectableNamespace.Name, configMapKey)
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+			&configMap,
+		})
+		reconciler.Sink = operator.SinkSecret
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: injectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		secret := getSecret(t, client, injectableNamespace.Name, configMapKey)
+		assert.Equal(t, map[string][]byte{"clusterName": []byte(clusterName)}, secret.Data)
+		assert.Equal(t, operator.ManagedByValue, secret.Labels[operator.ManagedByLabel])
+		assert.Empty(t, secret.OwnerReferences)
+		_, hasConfigMap := namespaceHasConfigMap(t, client, injectableNamespace.Name, configMapKey, nil)
+		assert.False(t, hasConfigMap)
+	})
+
+	t.Run("write both Secret and ConfigMap", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+		})
+		reconciler.Sink = operator.SinkBoth
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: injectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		secret := getSecret(t, client, injectableNamespace.Name, configMapKey)
+		assert.Equal(t, map[string][]byte{"clusterName": []byte(clusterName)}, secret.Data)
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, map[string]string{
+			"clusterName": clusterName,
+		})
+	})
+
+	t.Run("delete managed Secret when namespace opts out", func(t *testing.T) {
+		secret := managedSecret(nonInjectableNamespace.Name, configMapKey)
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&nonInjectableNamespace,
+			&secret,
+		})
+		reconciler.Sink = operator.SinkSecret
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: nonInjectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		err = client.Get(context.Background(), types.NamespacedName{Namespace: nonInjectableNamespace.Name, Name: configMapKey}, &corev1.Secret{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("leave Secrets untouched without Secret sink", func(t *testing.T) {
+		secret := managedSecret(injectableNamespace.Name, configMapKey)
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+			&secret,
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: injectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		getSecret(t, client, injectableNamespace.Name, configMapKey)
+	})
+
+	t.Run("leave Secrets untouched without Secret sink when namespace opts out", func(t *testing.T) {
+		secret := managedSecret(nonInjectableNamespace.Name, configMapKey)
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&nonInjectableNamespace,
+			&secret,
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: nonInjectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		getSecret(t, client, nonInjectableNamespace.Name, configMapKey)
+	})
+
+	t.Run("use configured ConfigMap name", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, "identity", []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: injectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, "identity", map[string]string{
+			"clusterName": clusterName,
+		})
+		_, hasConfigMap := namespaceHasConfigMap(t, client, injectableNamespace.Name, "cluster-identity", nil)
+		assert.False(t, hasConfigMap)
+	})
+
+	t.Run("keep a single ConfigMap with configured name on later reconciles", func(t *testing.T) {
+		reconciler, apiClient := setupNamespaceReconciler(t, "identity", []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+		})
+
+		for i := 0; i < 2; i++ {
+			result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: injectableNamespace.Name},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, ctrl.Result{}, result)
+		}
+
+		var configMaps corev1.ConfigMapList
+		err := apiClient.List(context.Background(), &configMaps, client.InNamespace(injectableNamespace.Name), client.MatchingLabelsSelector{Selector: operator.ManagedConfigMapSelector()})
+		require.NoError(t, err)
+		require.Len(t, configMaps.Items, 1)
+		assert.Equal(t, "identity", configMaps.Items[0].Name)
+		assert.NotContains(t, configMaps.Items[0].Annotations, operator.MigratedToAnnotation)
+	})
+
+	t.Run("move data from renamed ConfigMap and keep it during grace period", func(t *testing.T) {
+		oldConfigMap := managedConfigMap(injectableNamespace.Name, configMapKey)
+		reconciler, client := setupNamespaceReconciler(t, "identity", []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+			&oldConfigMap,
+		})
+		reconciler.MigrationGracePeriod = time.Hour
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: injectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		assert.InDelta(t, time.Hour, result.RequeueAfter, float64(time.Minute))
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, "identity", map[string]string{
+			"otherField":  "other",
+			"clusterName": clusterName,
+		})
+		migrated, hasConfigMap := namespaceHasConfigMap(t, client, injectableNamespace.Name, configMapKey, nil)
+		require.True(t, hasConfigMap)
+		assert.Equal(t, "identity", migrated.Annotations[operator.MigratedToAnnotation])
+		assert.NotEmpty(t, migrated.Annotations[operator.MigratedAtAnnotation])
+	})
+
+	t.Run("delete renamed ConfigMap after grace period", func(t *testing.T) {
+		oldConfigMap := managedConfigMap(injectableNamespace.Name, configMapKey)
+		oldConfigMap.Annotations = map[string]string{
+			operator.MigratedToAnnotation: "identity",
+			operator.MigratedAtAnnotation: time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+		}
+		reconciler, client := setupNamespaceReconciler(t, "identity", []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+			&oldConfigMap,
+		})
+		reconciler.MigrationGracePeriod = time.Hour
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: injectableNamespace.Name},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+		_, hasConfigMap := namespaceHasConfigMap(t, client, injectableNamespace.Name, configMapKey, nil)
+		assert.False(t, hasConfigMap)
+	})
+
+	t.Run("fail if cluster name cannot be detected", func(t *testing.T) {
+		reconciler, _ := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+		})
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+		assert.EqualError(t, err, "cluster identity 'cluster' is not ready: could not detect cluster name")
+		assert.Equal(t, ctrl.Result{}, result)
+	})
+
+	t.Run("not overwrite existing ConfigMap when strategies disagree", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+			&clusterIdentityConfigMap,
+		})
+		setConflict(t, client, "strategies disagree on the cluster name: 'a' (nodeLabel), 'b' (capi)")
+		recorder := record.NewFakeRecorder(1)
+		reconciler.Recorder = recorder
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+		assert.Equal(t, "Warning StrategiesDisagree cluster identity 'cluster' has a conflict, not overwriting ConfigMap 'injectable/cluster-identity': strategies disagree on the cluster name: 'a' (nodeLabel), 'b' (capi)", <-recorder.Events)
+
+		checkNamespacesForConfigMap(t, client, injectableNamespace.Name, configMapKey, clusterIdentityConfigMap.Data)
+	})
+
+	t.Run("overwrite existing ConfigMap when outvoted strategies disagree", func(t *testing.T) {
+		reconciler, client := setupNamespaceReconciler(t, configMapKey, []client.Object{
+			&injectableNamespace,
+			&nodeWithClusterNameLabel,
+			&clusterIdentityConfigMap,
+		})
+		setConflictReason(t, client, configv1alpha1.ReasonStrategiesOutvoted, "strategies disagree on the cluster name: 'a' (nodeLabel, kops) reached the quorum and outvoted 'b' (capi)")
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: injectableNamespace.Namespace,
+				Name:      injectableNamespace.Name,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+		configMap, _ := namespaceHasConfigMap(t, client, injectableNamespace.Name, configMapKey, nil)
+		assert.Equal(t, clusterName, configMap.Data["clusterName"])
+	})
+}
+
+// setConflict marks the cluster identity as conflicting.
+func setConflict(t *testing.T, client client.Client, message string) {
+	t.Helper()
+
+	setConflictReason(t, client, configv1alpha1.ReasonStrategiesDisagree, message)
+}
+
+// setConflictReason sets the Conflict condition of the cluster identity with
+// the reason.
+func setConflictReason(t *testing.T, client client.Client, reason, message string) {
+	t.Helper()
+
+	var identity configv1alpha1.ClusterIdentity
+	err := client.Get(context.Background(), types.NamespacedName{Name: "cluster"}, &identity)
+	require.NoError(t, err)
+
+	meta.SetStatusCondition(&identity.Status.Conditions, metav1.Condition{
+		Type:    configv1alpha1.ConditionTypeConflict,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+	err = client.Status().Update(context.Background(), &identity)
+	require.NoError(t, err)
+}
+
+func TestNamespaceControllerWatches(t *testing.T) {
+	var (
+		injectableNamespace    = injectableNamespace()
+		nonInjectableNamespace = nonInjectableNamespace()
+	)
+
+	t.Run("enqueue all injectable namespaces", func(t *testing.T) {
+		reconciler, _ := setupNamespaceReconciler(t, "cluster-identity", []client.Object{
+			&injectableNamespace,
+			&nonInjectableNamespace,
+		})
+		identity := clusterIdentity()
+
+		requests := reconciler.injectableNamespaces(context.Background(), &identity)
+
+		assert.Equal(t, []ctrl.Request{
+			{NamespacedName: types.NamespacedName{Name: injectableNamespace.Name}},
+		}, requests)
+	})
+
+	t.Run("enqueue namespace of managed ConfigMap", func(t *testing.T) {
+		configMap := clusterIdentityConfigMap(injectableNamespace.Name, "cluster-identity")
+
+		requests := configMapNamespace(context.Background(), &configMap)
+
+		assert.Equal(t, []ctrl.Request{
+			{NamespacedName: types.NamespacedName{Name: injectableNamespace.Name}},
+		}, requests)
+	})
+
+	t.Run("pass namespace opting out", func(t *testing.T) {
+		filter, err := operator.NewNamespaceFilter("team", "")
+		require.NoError(t, err)
+		oldNamespace := nonInjectableNamespace.DeepCopy()
+		oldNamespace.Labels = map[string]string{"team": "payments"}
+
+		changed := injectablePredicate{filter: filter}.Update(event.UpdateEvent{ObjectOld: oldNamespace, ObjectNew: &nonInjectableNamespace})
+
+		assert.True(t, changed)
+	})
+
+	t.Run("filter out namespaces never injected", func(t *testing.T) {
+		filter, err := operator.NewNamespaceFilter("team", "")
+		require.NoError(t, err)
+
+		changed := injectablePredicate{filter: filter}.Create(event.CreateEvent{Object: &nonInjectableNamespace})
+
+		assert.False(t, changed)
+	})
+
+	t.Run("pass identity changes", func(t *testing.T) {
+		oldIdentity := readyClusterIdentity("old")
+		newIdentity := readyClusterIdentity("new")
+
+		changed := identityChangedPredicate{}.Update(event.UpdateEvent{ObjectOld: &oldIdentity, ObjectNew: &newIdentity})
+
+		assert.True(t, changed)
+	})
+
+	t.Run("pass conflict changes", func(t *testing.T) {
+		oldIdentity := readyClusterIdentity("name")
+		newIdentity := readyClusterIdentity("name")
+		meta.SetStatusCondition(&newIdentity.Status.Conditions, metav1.Condition{
+			Type:   configv1alpha1.ConditionTypeConflict,
+			Status: metav1.ConditionTrue,
+			Reason: configv1alpha1.ReasonStrategiesDisagree,
+		})
+
+		changed := identityChangedPredicate{}.Update(event.UpdateEvent{ObjectOld: &oldIdentity, ObjectNew: &newIdentity})
+
+		assert.True(t, changed)
+	})
+
+	t.Run("pass conflict reason changes", func(t *testing.T) {
+		oldIdentity := readyClusterIdentity("name")
+		meta.SetStatusCondition(&oldIdentity.Status.Conditions, metav1.Condition{
+			Type:   configv1alpha1.ConditionTypeConflict,
+			Status: metav1.ConditionTrue,
+			Reason: configv1alpha1.ReasonStrategiesOutvoted,
+		})
+		newIdentity := readyClusterIdentity("name")
+		meta.SetStatusCondition(&newIdentity.Status.Conditions, metav1.Condition{
+			Type:   configv1alpha1.ConditionTypeConflict,
+			Status: metav1.ConditionTrue,
+			Reason: configv1alpha1.ReasonStrategiesDisagree,
+		})
+
+		changed := identityChangedPredicate{}.Update(event.UpdateEvent{ObjectOld: &oldIdentity, ObjectNew: &newIdentity})
+
+		assert.True(t, changed)
+	})
+
+	t.Run("filter out resyncs of the same identity", func(t *testing.T) {
+		oldIdentity := readyClusterIdentity("name")
+		newIdentity := readyClusterIdentity("name")
+		now := metav1.Now()
+		newIdentity.Status.LastObservedTime = &now
+
+		changed := identityChangedPredicate{}.Update(event.UpdateEvent{ObjectOld: &oldIdentity, ObjectNew: &newIdentity})
+
+		assert.False(t, changed)
+	})
+}
+
+// readyClusterIdentity returns a ready cluster identity with the cluster name.
+func readyClusterIdentity(clusterName string) configv1alpha1.ClusterIdentity {
+	identity := clusterIdentity()
+	identity.Status.ClusterName = clusterName
+	meta.SetStatusCondition(&identity.Status.Conditions, metav1.Condition{
+		Type:   configv1alpha1.ConditionTypeReady,
+		Status: metav1.ConditionTrue,
+		Reason: configv1alpha1.ReasonDetected,
 	})
+	return identity
 }
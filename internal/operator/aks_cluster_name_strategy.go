@@ -0,0 +1,164 @@
+package operator
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	aksClusterLabel          = "kubernetes.azure.com/cluster"
+	aksNodeResourceGroupHead = "MC"
+	aksAPIServerDomain       = ".azmk8s.io"
+	azureCloudProvider       = "azure"
+	azureProviderIDPrefix    = "azure:///"
+
+	coreDNSAutoScalerLabelKey   = "k8s-app"
+	coreDNSAutoScalerLabelValue = "coredns-autoscaler"
+	coreDNSAutoScalerNamespace  = "kube-system"
+)
+
+// aksAPIServerEnvs are the environment variables injected into every pod that
+// hold the FQDN of the AKS API server.
+var aksAPIServerEnvs = []string{"KUBERNETES_PORT_443_TCP_ADDR", "KUBERNETES_SERVICE_HOST"}
+
+// aksStrategy detects the cluster name on AKS from the node resource group in
+// the kubernetes.azure.com/cluster node label and falls back to the DNS prefix
+// of the API server FQDN seen by the coredns-autoscaler pod.
+type aksStrategy struct{}
+
+func (a *aksStrategy) Name() string {
+	return "aks"
+}
+
+func (a *aksStrategy) Detect(ctx context.Context, apiClient client.Client) (Identity, error) {
+	node, found, err := getAKSNode(ctx, apiClient)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if found {
+		identity, ok := aksIdentityFromNodeResourceGroup(node.Labels[aksClusterLabel])
+		if ok {
+			identity.Account = azureSubscriptionFromProviderID(node.Spec.ProviderID)
+			return identity, nil
+		}
+	}
+
+	pod, found, err := getCoreDNSAutoscalerPod(ctx, apiClient)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if !found {
+		return Identity{}, nil
+	}
+
+	identity, _ := aksIdentityFromFQDN(aksAPIServerFQDNFromPod(pod))
+	return identity, nil
+}
+
+func getAKSNode(ctx context.Context, apiClient client.Client) (corev1.Node, bool, error) {
+	var nodeList corev1.NodeList
+	err := apiClient.List(ctx, &nodeList, client.HasLabels{aksClusterLabel})
+	if err != nil {
+		return corev1.Node{}, false, err
+	}
+
+	for _, node := range nodeList.Items {
+		if node.Labels[aksClusterLabel] != "" {
+			return node, true, nil
+		}
+	}
+
+	return corev1.Node{}, false, nil
+}
+
+func getCoreDNSAutoscalerPod(ctx context.Context, apiClient client.Client) (corev1.Pod, bool, error) {
+	var podList corev1.PodList
+	err := apiClient.List(ctx, &podList, client.MatchingLabels{coreDNSAutoScalerLabelKey: coreDNSAutoScalerLabelValue}, client.InNamespace(coreDNSAutoScalerNamespace))
+	if err != nil {
+		return corev1.Pod{}, false, err
+	}
+
+	for _, pod := range podList.Items {
+		if strings.HasPrefix(pod.Name, "coredns-autoscaler") {
+			return pod, true, nil
+		}
+	}
+
+	return corev1.Pod{}, false, nil
+}
+
+// aksAPIServerFQDNFromPod returns the FQDN of the API server from the
+// environment of a pod.
+func aksAPIServerFQDNFromPod(pod corev1.Pod) string {
+	for _, name := range aksAPIServerEnvs {
+		for _, c := range pod.Spec.Containers {
+			for _, e := range c.Env {
+				if e.Name == name && e.Value != "" {
+					return e.Value
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// aksIdentityFromNodeResourceGroup parses a node resource group on the form
+// MC_<resource-group>_<cluster>_<region>. Resource group names may contain
+// underscores so the cluster name and region are read from the end.
+func aksIdentityFromNodeResourceGroup(nodeResourceGroup string) (Identity, bool) {
+	parts := strings.Split(nodeResourceGroup, "_")
+	if len(parts) < 4 || !strings.EqualFold(parts[0], aksNodeResourceGroupHead) {
+		return Identity{}, false
+	}
+
+	last := len(parts) - 1
+	return Identity{
+		ClusterName:   parts[last-1],
+		CloudProvider: azureCloudProvider,
+		Region:        parts[last],
+		Attributes: map[string]string{
+			AttributeResourceGroup: strings.Join(parts[1:last-1], "_"),
+		},
+	}, true
+}
+
+// azureSubscriptionFromProviderID extracts the subscription ID from an Azure
+// provider ID on the form azure:///subscriptions/<subscription>/resourceGroups/...
+func azureSubscriptionFromProviderID(providerID string) string {
+	parts := strings.Split(strings.TrimPrefix(providerID, azureProviderIDPrefix), "/")
+	if len(parts) < 2 || !strings.EqualFold(parts[0], "subscriptions") {
+		return ""
+	}
+	return parts[1]
+}
+
+// aksIdentityFromFQDN parses an API server FQDN on the form
+// <dns-prefix>-<hash>.hcp.<region>.azmk8s.io, or
+// <dns-prefix>-<hash>.<guid>.privatelink.<region>.azmk8s.io for private
+// clusters, and returns the DNS prefix as the cluster name.
+func aksIdentityFromFQDN(fqdn string) (Identity, bool) {
+	if !strings.HasSuffix(fqdn, aksAPIServerDomain) {
+		return Identity{}, false
+	}
+
+	labels := strings.Split(strings.TrimSuffix(fqdn, aksAPIServerDomain), ".")
+	if len(labels) < 3 {
+		return Identity{}, false
+	}
+
+	index := strings.LastIndex(labels[0], "-")
+	if index <= 0 {
+		return Identity{}, false
+	}
+
+	return Identity{
+		ClusterName:   labels[0][:index],
+		CloudProvider: azureCloudProvider,
+		Region:        labels[len(labels)-1],
+	}, true
+}
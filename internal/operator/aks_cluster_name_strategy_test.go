@@ -0,0 +1,128 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAKSIdentityFromNodeResourceGroup(t *testing.T) {
+	tt := []struct {
+		name              string
+		nodeResourceGroup string
+		expected          Identity
+		ok                bool
+	}{
+		{
+			name:              "default node resource group",
+			nodeResourceGroup: "MC_lunar-prod_prod-weu-01_westeurope",
+			expected: Identity{
+				ClusterName:   "prod-weu-01",
+				CloudProvider: "azure",
+				Region:        "westeurope",
+				Attributes: map[string]string{
+					"resourceGroup": "lunar-prod",
+				},
+			},
+			ok: true,
+		},
+		{
+			name:              "resource group with underscores",
+			nodeResourceGroup: "MC_lunar_prod_rg_prod-weu-01_westeurope",
+			expected: Identity{
+				ClusterName:   "prod-weu-01",
+				CloudProvider: "azure",
+				Region:        "westeurope",
+				Attributes: map[string]string{
+					"resourceGroup": "lunar_prod_rg",
+				},
+			},
+			ok: true,
+		},
+		{
+			name:              "custom node resource group",
+			nodeResourceGroup: "prod-weu-01-nodes",
+			ok:                false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			identity, ok := aksIdentityFromNodeResourceGroup(tc.nodeResourceGroup)
+
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, identity)
+		})
+	}
+}
+
+func TestAKSIdentityFromFQDN(t *testing.T) {
+	tt := []struct {
+		name     string
+		fqdn     string
+		expected Identity
+		ok       bool
+	}{
+		{
+			name: "public cluster",
+			fqdn: "prod-weu-01-a1b2c3d4.hcp.westeurope.azmk8s.io",
+			expected: Identity{
+				ClusterName:   "prod-weu-01",
+				CloudProvider: "azure",
+				Region:        "westeurope",
+			},
+			ok: true,
+		},
+		{
+			name: "private cluster",
+			fqdn: "prod-weu-01-a1b2c3d4.5e6f7a8b-1234-5678-9abc-def012345678.privatelink.westeurope.azmk8s.io",
+			expected: Identity{
+				ClusterName:   "prod-weu-01",
+				CloudProvider: "azure",
+				Region:        "westeurope",
+			},
+			ok: true,
+		},
+		{
+			name: "not an AKS API server",
+			fqdn: "api.k8s.lunar.tech",
+			ok:   false,
+		},
+		{
+			name: "missing hash",
+			fqdn: "prod.hcp.westeurope.azmk8s.io",
+			ok:   false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			identity, ok := aksIdentityFromFQDN(tc.fqdn)
+
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, identity)
+		})
+	}
+}
+
+func TestAzureSubscriptionFromProviderID(t *testing.T) {
+	tt := []struct {
+		name       string
+		providerID string
+		expected   string
+	}{
+		{
+			name:       "scale set instance",
+			providerID: "azure:///subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/mc_lunar-prod_prod-weu-01_westeurope/providers/Microsoft.Compute/virtualMachineScaleSets/aks-nodepool1-12345678-vmss/virtualMachines/0",
+			expected:   "00000000-0000-0000-0000-000000000000",
+		},
+		{
+			name:       "other provider",
+			providerID: "aws:///eu-west-1a/i-0123456789abcdef0",
+			expected:   "",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, azureSubscriptionFromProviderID(tc.providerID))
+		})
+	}
+}
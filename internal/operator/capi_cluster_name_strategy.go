@@ -0,0 +1,134 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	capiClusterNameLabel           = "cluster.x-k8s.io/cluster-name"
+	capiClusterNamespaceAnnotation = "cluster.x-k8s.io/cluster-namespace"
+
+	AttributeClusterAPINamespace = "clusterAPINamespace"
+)
+
+var capiClusterListGVK = schema.GroupVersionKind{
+	Group:   "cluster.x-k8s.io",
+	Version: "v1beta1",
+	Kind:    "ClusterList",
+}
+
+// capiStrategy detects the cluster name of Cluster API workload clusters from
+// the cluster-name label or annotation set on nodes by the Machine controller.
+// In self-managed clusters the matching Cluster object is used to find the
+// namespace and control plane endpoint of the cluster.
+type capiStrategy struct{}
+
+func (c *capiStrategy) Name() string {
+	return "capi"
+}
+
+func (c *capiStrategy) Detect(ctx context.Context, apiClient client.Client) (Identity, error) {
+	node, found, err := getCAPINode(ctx, apiClient)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if !found {
+		return Identity{}, nil
+	}
+
+	identity := cloudNodeIdentity(node)
+	identity.ClusterName = capiNodeClusterName(node)
+	namespace := node.Annotations[capiClusterNamespaceAnnotation]
+
+	cluster, found, err := getCAPICluster(ctx, apiClient, identity.ClusterName, namespace)
+	if err != nil {
+		return Identity{}, err
+	}
+	if found {
+		namespace = cluster.GetNamespace()
+		identity.SetAttribute(AttributeControlPlaneEndpoint, capiControlPlaneEndpoint(cluster))
+	}
+	identity.SetAttribute(AttributeClusterAPINamespace, namespace)
+
+	return identity, nil
+}
+
+// getCAPINode returns the first node carrying the Cluster API cluster-name
+// label or annotation.
+func getCAPINode(ctx context.Context, apiClient client.Client) (corev1.Node, bool, error) {
+	var nodeList corev1.NodeList
+	err := apiClient.List(ctx, &nodeList)
+	if err != nil {
+		return corev1.Node{}, false, err
+	}
+
+	for _, node := range nodeList.Items {
+		if capiNodeClusterName(node) != "" {
+			return node, true, nil
+		}
+	}
+
+	return corev1.Node{}, false, nil
+}
+
+func capiNodeClusterName(node corev1.Node) string {
+	if clusterName := node.Labels[capiClusterNameLabel]; clusterName != "" {
+		return clusterName
+	}
+	return node.Annotations[capiClusterNameLabel]
+}
+
+// getCAPICluster returns the Cluster object describing the cluster itself. It
+// is only found in self-managed clusters where Cluster API has been pivoted
+// into the workload cluster. If the namespace is unknown the cluster name must
+// be unique across namespaces.
+func getCAPICluster(ctx context.Context, apiClient client.Client, clusterName, namespace string) (unstructured.Unstructured, bool, error) {
+	clusterList := &unstructured.UnstructuredList{}
+	clusterList.SetGroupVersionKind(capiClusterListGVK)
+	var opts []client.ListOption
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	err := apiClient.List(ctx, clusterList, opts...)
+	if err != nil {
+		// the Cluster CRD is only installed when Cluster API runs in the cluster
+		if isAPINotInstalled(err) {
+			return unstructured.Unstructured{}, false, nil
+		}
+		return unstructured.Unstructured{}, false, fmt.Errorf("list Cluster API clusters: %w", err)
+	}
+
+	var matches []unstructured.Unstructured
+	for _, cluster := range clusterList.Items {
+		if cluster.GetName() == clusterName {
+			matches = append(matches, cluster)
+		}
+	}
+	if len(matches) != 1 {
+		return unstructured.Unstructured{}, false, nil
+	}
+	return matches[0], true, nil
+}
+
+// capiControlPlaneEndpoint returns the spec.controlPlaneEndpoint of a Cluster
+// as host:port.
+func capiControlPlaneEndpoint(cluster unstructured.Unstructured) string {
+	host := nestedString(&cluster, "spec", "controlPlaneEndpoint", "host")
+	if host == "" {
+		return ""
+	}
+	port, found, _ := unstructured.NestedInt64(cluster.Object, "spec", "controlPlaneEndpoint", "port")
+	if !found || port == 0 {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.FormatInt(port, 10))
+}
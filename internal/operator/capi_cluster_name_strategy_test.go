@@ -0,0 +1,147 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestCAPIStrategyDetect(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		node = corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "prod-md-0-7b9f4-x2k8l",
+				Labels: map[string]string{
+					capiClusterNameLabel: "prod",
+				},
+				Annotations: map[string]string{
+					capiClusterNamespaceAnnotation: "clusters",
+				},
+			},
+		}
+	)
+
+	tt := []struct {
+		name     string
+		objects  []client.Object
+		expected Identity
+	}{
+		{
+			name:     "no Cluster API nodes",
+			objects:  nil,
+			expected: Identity{},
+		},
+		{
+			name:    "workload cluster",
+			objects: []client.Object{&node},
+			expected: Identity{
+				ClusterName: "prod",
+				Attributes: map[string]string{
+					AttributeClusterAPINamespace: "clusters",
+				},
+			},
+		},
+		{
+			name: "self-managed cluster",
+			objects: []client.Object{
+				&node,
+				capiCluster("prod", "clusters"),
+				capiCluster("prod", "other"),
+				capiCluster("dev", "clusters"),
+			},
+			expected: Identity{
+				ClusterName: "prod",
+				Attributes: map[string]string{
+					AttributeClusterAPINamespace:  "clusters",
+					AttributeControlPlaneEndpoint: "prod.lunar.tech:6443",
+				},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := &capiStrategy{}
+			apiClient := fake.NewClientBuilder().WithObjects(tc.objects...).Build()
+
+			identity, err := sut.Detect(ctx, apiClient)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, identity)
+		})
+	}
+	t.Run("workload cluster when Cluster API group is not served", func(t *testing.T) {
+		sut := &capiStrategy{}
+		apiClient := fake.NewClientBuilder().WithObjects(&node).WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, client client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				if _, ok := list.(*unstructured.UnstructuredList); !ok {
+					return client.List(ctx, list, opts...)
+				}
+				// the error returned by the dynamic REST mapper of
+				// controller-runtime v0.15.0
+				return fmt.Errorf("failed to get API group resources: %w", &discovery.ErrGroupDiscoveryFailed{
+					Groups: map[schema.GroupVersion]error{
+						capiClusterListGVK.GroupVersion(): apierrors.NewNotFound(schema.GroupResource{}, ""),
+					},
+				})
+			},
+		}).Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Identity{
+			ClusterName: "prod",
+			Attributes: map[string]string{
+				AttributeClusterAPINamespace: "clusters",
+			},
+		}, identity)
+	})
+
+	t.Run("workload cluster on API server without Cluster CRD", func(t *testing.T) {
+		sut := &capiStrategy{}
+		apiClient := startTestEnv(t)
+		require.NoError(t, apiClient.Create(ctx, node.DeepCopy()))
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Identity{
+			ClusterName: "prod",
+			Attributes: map[string]string{
+				AttributeClusterAPINamespace: "clusters",
+			},
+		}, identity)
+	})
+}
+
+func capiCluster(name, namespace string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "cluster.x-k8s.io/v1beta1",
+			"kind":       "Cluster",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"controlPlaneEndpoint": map[string]interface{}{
+					"host": name + ".lunar.tech",
+					"port": int64(6443),
+				},
+			},
+		},
+	}
+}
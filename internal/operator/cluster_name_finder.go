@@ -2,42 +2,285 @@ package operator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// clusterIDNamespace is the namespace whose UID is used as the cluster ID.
+const clusterIDNamespace = "kube-system"
+
+// ErrClusterNameNotDetected is returned when none of the strategies could
+// detect the cluster name.
+var ErrClusterNameNotDetected = errors.New("could not detect cluster name")
+
 type clusterNameStrategy interface {
-	GetClusterName(ctx context.Context, apiClient client.Client) (string, error)
+	// Name returns the name of the strategy as reported on the ClusterIdentity
+	// status.
+	Name() string
+	// Detect returns the cluster name and any other identity fields the
+	// strategy knows about. An empty cluster name means the strategy does not
+	// apply.
+	Detect(ctx context.Context, apiClient client.Client) (Identity, error)
+}
+
+// Detection is the result of a successful cluster name detection.
+type Detection struct {
+	Identity
+	// Strategy is the name of the strategy that found the cluster name.
+	Strategy string
+	// Outvoted are the cluster names detected by strategies that disagreed
+	// with the quorum in consensus mode.
+	Outvoted []Candidate
+}
+
+// StrategyOutcome is the outcome of running a single strategy.
+type StrategyOutcome struct {
+	Strategy string
+	// Err is the error returned by the strategy. It is nil if the strategy did
+	// not apply.
+	Err error
+}
+
+func (o StrategyOutcome) String() string {
+	if o.Err == nil {
+		return fmt.Sprintf("%s: not applicable", o.Strategy)
+	}
+	return fmt.Sprintf("%s: %v", o.Strategy, o.Err)
+}
+
+// DetectionError is returned in fallthrough mode when none of the strategies
+// detected the cluster name. It reports the outcome of every strategy.
+//
+// It matches ErrClusterNameNotDetected with errors.Is and the errors of the
+// failed strategies with errors.Is and errors.As.
+type DetectionError struct {
+	Outcomes []StrategyOutcome
+}
+
+func (e *DetectionError) Error() string {
+	outcomes := make([]string, 0, len(e.Outcomes))
+	for _, outcome := range e.Outcomes {
+		outcomes = append(outcomes, outcome.String())
+	}
+	return fmt.Sprintf("%v: %s", ErrClusterNameNotDetected, strings.Join(outcomes, "; "))
+}
+
+func (e *DetectionError) Is(target error) bool {
+	if target == ErrClusterNameNotDetected {
+		return true
+	}
+	for _, outcome := range e.Outcomes {
+		if outcome.Err != nil && errors.Is(outcome.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *DetectionError) As(target interface{}) bool {
+	for _, outcome := range e.Outcomes {
+		if outcome.Err != nil && errors.As(outcome.Err, target) {
+			return true
+		}
+	}
+	return false
 }
 
 type ClusterNameFinder struct {
 	strategies []clusterNameStrategy
+	// continueOnError continues with the next strategy when a strategy fails
+	// instead of returning its error.
+	continueOnError bool
+	// quorum is the number of strategies that must agree on the cluster name.
+	// Consensus mode is disabled when zero.
+	quorum int
 }
 
 func (c *ClusterNameFinder) GetClusterName(ctx context.Context, apiClient client.Client) (string, error) {
+	detection, err := c.Detect(ctx, apiClient)
+	if err != nil {
+		return "", err
+	}
+
+	return detection.ClusterName, nil
+}
+
+// Detect runs the strategies in order and returns the first cluster name found
+// along with the strategy that found it. In consensus mode all strategies are
+// run, see detectConsensus.
+//
+// By default the error of a failing strategy is returned right away. In
+// fallthrough mode the remaining strategies are tried and a *DetectionError
+// is returned if none of them detected the cluster name.
+func (c *ClusterNameFinder) Detect(ctx context.Context, apiClient client.Client) (Detection, error) {
+	if c.quorum > 0 {
+		return c.detectConsensus(ctx, apiClient)
+	}
+
+	outcomes := make([]StrategyOutcome, 0, len(c.strategies))
 	for _, strategy := range c.strategies {
-		clusterName, err := strategy.GetClusterName(ctx, apiClient)
-		if err != nil {
-			return "", err
+		identity, err := strategy.Detect(ctx, apiClient)
+		if err != nil && !c.continueOnError {
+			return Detection{}, err
 		}
 
-		if clusterName == "" {
+		if err != nil || identity.ClusterName == "" {
+			outcomes = append(outcomes, StrategyOutcome{
+				Strategy: strategy.Name(),
+				Err:      err,
+			})
 			continue
 		}
 
-		return clusterName, nil
+		return c.complete(ctx, apiClient, identity, strategy.Name())
 	}
 
-	return "", fmt.Errorf("could not detect cluster name")
+	return Detection{}, c.notDetected(outcomes)
 }
 
-func NewClusterNameFinder() *ClusterNameFinder {
-	return &ClusterNameFinder{
-		strategies: []clusterNameStrategy{
-			&kubeControllerStrategy{},
-			&coreDNSClusterNameStrategy{},
-			&nodeLabelStrategy{},
-		},
+// complete adds the cluster wide fields to a detected identity.
+func (c *ClusterNameFinder) complete(ctx context.Context, apiClient client.Client, identity Identity, strategy string) (Detection, error) {
+	var err error
+	identity.ClusterID, err = GetClusterID(ctx, apiClient)
+	if err != nil {
+		return Detection{}, err
+	}
+	zones, err := getNodeZones(ctx, apiClient)
+	if err != nil {
+		return Detection{}, err
+	}
+	if len(zones) > 0 {
+		identity.Zones = zones
 	}
+
+	return Detection{
+		Identity: identity,
+		Strategy: strategy,
+	}, nil
+}
+
+// notDetected returns the error reported when none of the strategies detected
+// the cluster name.
+func (c *ClusterNameFinder) notDetected(outcomes []StrategyOutcome) error {
+	if c.continueOnError {
+		return &DetectionError{Outcomes: outcomes}
+	}
+	return ErrClusterNameNotDetected
+}
+
+// GetClusterID returns the UID of the kube-system namespace. An empty string is
+// returned if the namespace does not exist.
+func GetClusterID(ctx context.Context, apiClient client.Client) (string, error) {
+	var namespace corev1.Namespace
+	err := apiClient.Get(ctx, types.NamespacedName{Name: clusterIDNamespace}, &namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("get namespace '%s': %w", clusterIDNamespace, err)
+	}
+
+	return string(namespace.UID), nil
+}
+
+// getNodeZones returns the zones of all nodes from their topology labels.
+func getNodeZones(ctx context.Context, apiClient client.Client) ([]string, error) {
+	var nodeList corev1.NodeList
+	err := apiClient.List(ctx, &nodeList, client.HasLabels{topologyZoneLabel})
+	if err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+
+	zones := make([]string, 0, len(nodeList.Items))
+	for _, node := range nodeList.Items {
+		zones = append(zones, node.Labels[topologyZoneLabel])
+	}
+	return normalizeZones(zones), nil
+}
+
+// DefaultStrategies are the names of the strategies tried when no strategies
+// are configured, in the order they are tried.
+var DefaultStrategies = []string{
+	"kubeController",
+	"aks",
+	"nodeLabel",
+	"eks",
+	"gke",
+	"kubeadm",
+	"kops",
+	"openshift",
+	"capi",
+}
+
+// strategyFactories create the strategies by name.
+var strategyFactories = map[string]func(options ClusterNameFinderOptions) clusterNameStrategy{
+	"kubeController": func(ClusterNameFinderOptions) clusterNameStrategy { return &kubeControllerStrategy{} },
+	"aks":            func(ClusterNameFinderOptions) clusterNameStrategy { return &aksStrategy{} },
+	"nodeLabel":      func(ClusterNameFinderOptions) clusterNameStrategy { return &nodeLabelStrategy{} },
+	"eks":            func(ClusterNameFinderOptions) clusterNameStrategy { return &eksStrategy{} },
+	"gke": func(options ClusterNameFinderOptions) clusterNameStrategy {
+		return newGKEStrategy(options.GKEMetadataEndpoint)
+	},
+	"kubeadm":   func(ClusterNameFinderOptions) clusterNameStrategy { return &kubeadmStrategy{} },
+	"kops":      func(ClusterNameFinderOptions) clusterNameStrategy { return &kopsStrategy{} },
+	"openshift": func(ClusterNameFinderOptions) clusterNameStrategy { return &openShiftStrategy{} },
+	"capi":      func(ClusterNameFinderOptions) clusterNameStrategy { return &capiStrategy{} },
+}
+
+// ClusterNameFinderOptions configures the strategies of a ClusterNameFinder.
+type ClusterNameFinderOptions struct {
+	// Strategies are the names of the enabled strategies in the order they
+	// are tried. DefaultStrategies are used when empty.
+	Strategies []string
+	// GKEMetadataEndpoint is the base URL of the GCE metadata server, e.g.
+	// http://metadata.google.internal. The metadata server is not queried
+	// when empty.
+	GKEMetadataEndpoint string
+	// Fallthrough continues with the next strategy when a strategy fails. The
+	// errors are only returned if no strategy detected the cluster name.
+	Fallthrough bool
+	// Quorum enables consensus mode when larger than zero. All strategies are
+	// run and at least Quorum of them must detect the same cluster name.
+	Quorum int
+}
+
+// NewClusterNameFinder returns a ClusterNameFinder trying the configured
+// strategies in order. An error is returned if a strategy name is unknown or
+// listed more than once, or if the quorum cannot be reached.
+func NewClusterNameFinder(options ClusterNameFinderOptions) (*ClusterNameFinder, error) {
+	names := options.Strategies
+	if len(names) == 0 {
+		names = DefaultStrategies
+	}
+
+	strategies := make([]clusterNameStrategy, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		factory, ok := strategyFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown strategy '%s': valid strategies are %s", name, strings.Join(DefaultStrategies, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("strategy '%s' is listed more than once", name)
+		}
+		seen[name] = true
+		strategies = append(strategies, factory(options))
+	}
+
+	if options.Quorum < 0 || options.Quorum > len(strategies) {
+		return nil, fmt.Errorf("quorum %d must be between 0 and the number of strategies %d", options.Quorum, len(strategies))
+	}
+
+	return &ClusterNameFinder{
+		strategies:      strategies,
+		continueOnError: options.Fallthrough,
+		quorum:          options.Quorum,
+	}, nil
 }
@@ -2,10 +2,15 @@ package operator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
@@ -79,22 +84,205 @@ func TestClusterNameFinderGetClusterName(t *testing.T) {
 	})
 }
 
+func TestClusterNameFinderDetect(t *testing.T) {
+	var (
+		ctx = context.Background()
+	)
+
+	t.Run("Return strategy that found the cluster name", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{
+				newNamedFakeStrategy("first", "", nil),
+				newNamedFakeStrategy("second", "clusterName", nil)},
+		}
+		apiClient := fake.NewClientBuilder().Build()
+
+		detection, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Detection{
+			Identity: Identity{ClusterName: "clusterName"},
+			Strategy: "second",
+		}, detection)
+	})
+
+	t.Run("Return zones of all nodes", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{newNamedFakeStrategy("fake", "clusterName", nil)},
+		}
+		apiClient := fake.NewClientBuilder().WithObjects(
+			nodeInZone("node-1", "eu-west-1b"),
+			nodeInZone("node-2", "eu-west-1a"),
+			nodeInZone("node-3", "eu-west-1b"),
+		).Build()
+
+		detection, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"eu-west-1a", "eu-west-1b"}, detection.Zones)
+	})
+
+	t.Run("Return kube-system namespace UID as cluster ID", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{newNamedFakeStrategy("fake", "clusterName", nil)},
+		}
+		apiClient := fake.NewClientBuilder().WithObjects(&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "kube-system",
+				UID:  "6a9cbbb4-0b1e-4c6a-9f0e-2b7e1c3f4d5a",
+			},
+		}).Build()
+
+		detection, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Detection{
+			Identity: Identity{
+				ClusterName: "clusterName",
+				ClusterID:   "6a9cbbb4-0b1e-4c6a-9f0e-2b7e1c3f4d5a",
+			},
+			Strategy: "fake",
+		}, detection)
+	})
+}
+
+func TestClusterNameFinderDetectFallthrough(t *testing.T) {
+	var (
+		ctx          = context.Background()
+		forbiddenErr = apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", fmt.Errorf("access denied"))
+	)
+
+	t.Run("Return cluster name from strategy after first strategy fails", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{
+				newNamedFakeStrategy("first", "", forbiddenErr),
+				newNamedFakeStrategy("second", "clusterName", nil)},
+			continueOnError: true,
+		}
+		apiClient := fake.NewClientBuilder().Build()
+
+		detection, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "clusterName", detection.ClusterName)
+		assert.Equal(t, "second", detection.Strategy)
+	})
+
+	t.Run("Return outcome of every strategy when nothing is detected", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{
+				newNamedFakeStrategy("first", "", forbiddenErr),
+				newNamedFakeStrategy("second", "", nil)},
+			continueOnError: true,
+		}
+		apiClient := fake.NewClientBuilder().Build()
+
+		_, err := sut.Detect(ctx, apiClient)
+
+		assert.EqualError(t, err, fmt.Sprintf("could not detect cluster name: first: %v; second: not applicable", forbiddenErr))
+		assert.ErrorIs(t, err, ErrClusterNameNotDetected)
+		assert.True(t, apierrors.IsForbidden(err))
+
+		var detectionErr *DetectionError
+		assert.True(t, errors.As(err, &detectionErr))
+		assert.Equal(t, []StrategyOutcome{
+			{Strategy: "first", Err: forbiddenErr},
+			{Strategy: "second"},
+		}, detectionErr.Outcomes)
+
+		var statusErr *apierrors.StatusError
+		assert.True(t, errors.As(err, &statusErr))
+	})
+}
+
+func TestNewClusterNameFinder(t *testing.T) {
+	t.Run("Use default strategies", func(t *testing.T) {
+		sut, err := NewClusterNameFinder(ClusterNameFinderOptions{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultStrategies, strategyNames(sut))
+	})
+
+	t.Run("Use configured strategies in order", func(t *testing.T) {
+		sut, err := NewClusterNameFinder(ClusterNameFinderOptions{
+			Strategies: []string{"kubeadm", " nodeLabel"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"kubeadm", "nodeLabel"}, strategyNames(sut))
+	})
+
+	t.Run("Reject unknown strategy", func(t *testing.T) {
+		_, err := NewClusterNameFinder(ClusterNameFinderOptions{
+			Strategies: []string{"nodeLabel", "coreDns"},
+		})
+
+		assert.EqualError(t, err, "unknown strategy 'coreDns': valid strategies are kubeController, aks, nodeLabel, eks, gke, kubeadm, kops, openshift, capi")
+	})
+
+	t.Run("Reject quorum larger than the number of strategies", func(t *testing.T) {
+		_, err := NewClusterNameFinder(ClusterNameFinderOptions{
+			Strategies: []string{"nodeLabel", "kubeadm"},
+			Quorum:     3,
+		})
+
+		assert.EqualError(t, err, "quorum 3 must be between 0 and the number of strategies 2")
+	})
+
+	t.Run("Reject duplicate strategy", func(t *testing.T) {
+		_, err := NewClusterNameFinder(ClusterNameFinderOptions{
+			Strategies: []string{"nodeLabel", "nodeLabel"},
+		})
+
+		assert.EqualError(t, err, "strategy 'nodeLabel' is listed more than once")
+	})
+}
+
+func strategyNames(finder *ClusterNameFinder) []string {
+	names := make([]string, 0, len(finder.strategies))
+	for _, strategy := range finder.strategies {
+		names = append(names, strategy.Name())
+	}
+	return names
+}
+
+func nodeInZone(name, zone string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				"topology.kubernetes.io/zone": zone,
+			},
+		},
+	}
+}
+
 type fakeStrategy struct {
+	name        string
 	clusterName string
 	err         error
 }
 
 func newFakeStrategy(clusterName string, err error) *fakeStrategy {
+	return newNamedFakeStrategy("fake", clusterName, err)
+}
+
+func newNamedFakeStrategy(name, clusterName string, err error) *fakeStrategy {
 	return &fakeStrategy{
+		name:        name,
 		clusterName: clusterName,
 		err:         err,
 	}
 }
 
-func (f *fakeStrategy) GetClusterName(context.Context, client.Client) (string, error) {
+func (f *fakeStrategy) Name() string {
+	return f.name
+}
+
+func (f *fakeStrategy) Detect(context.Context, client.Client) (Identity, error) {
 	if f.err != nil {
-		return "", f.err
+		return Identity{}, f.err
 	}
 
-	return f.clusterName, nil
+	return Identity{ClusterName: f.clusterName}, nil
 }
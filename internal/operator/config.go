@@ -0,0 +1,38 @@
+package operator
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Config is the operator configuration read from the file given with
+// --operator-config.
+type Config struct {
+	// Strategies are the names of the enabled strategies in the order they
+	// are tried. DefaultStrategies are used when empty.
+	Strategies []string `json:"strategies,omitempty"`
+	// Quorum enables consensus mode when larger than zero. All strategies are
+	// run and at least Quorum of them must detect the same cluster name.
+	Quorum int `json:"quorum,omitempty"`
+	// Templates are Go templates by ConfigMap key rendered against the
+	// detected identity, see ParseTemplates.
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// LoadConfig reads the configuration file at path. Unknown fields are
+// rejected.
+func LoadConfig(path string) (Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read config file '%s': %w", path, err)
+	}
+
+	var config Config
+	err = yaml.UnmarshalStrict(content, &config)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse config file '%s': %w", path, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,39 @@
+package operator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("Read strategies", func(t *testing.T) {
+		path := writeConfig(t, "strategies:\n- nodeLabel\n- kubeadm\n")
+
+		config, err := LoadConfig(path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Config{Strategies: []string{"nodeLabel", "kubeadm"}}, config)
+	})
+
+	t.Run("Reject unknown fields", func(t *testing.T) {
+		path := writeConfig(t, "strategy:\n- nodeLabel\n")
+
+		_, err := LoadConfig(path)
+
+		assert.ErrorContains(t, err, `unknown field "strategy"`)
+	})
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,136 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Candidate is a cluster name detected by one or more strategies.
+type Candidate struct {
+	ClusterName string
+	// Strategies are the names of the strategies that detected the cluster
+	// name in the order they were run.
+	Strategies []string
+}
+
+func (c Candidate) String() string {
+	return fmt.Sprintf("'%s' (%s)", c.ClusterName, strings.Join(c.Strategies, ", "))
+}
+
+// ConflictError is returned in consensus mode when strategies detect
+// different cluster names.
+type ConflictError struct {
+	Candidates []Candidate
+}
+
+func (e *ConflictError) Error() string {
+	candidates := make([]string, 0, len(e.Candidates))
+	for _, candidate := range e.Candidates {
+		candidates = append(candidates, candidate.String())
+	}
+	return fmt.Sprintf("strategies disagree on the cluster name: %s", strings.Join(candidates, ", "))
+}
+
+// QuorumError is returned in consensus mode when fewer strategies than the
+// quorum detected the cluster name. It matches ErrClusterNameNotDetected with
+// errors.Is.
+type QuorumError struct {
+	Quorum    int
+	Candidate Candidate
+}
+
+func (e *QuorumError) Error() string {
+	return fmt.Sprintf("%v: %s detected by %d strategies but the quorum is %d", ErrClusterNameNotDetected, e.Candidate, len(e.Candidate.Strategies), e.Quorum)
+}
+
+func (e *QuorumError) Is(target error) bool {
+	return target == ErrClusterNameNotDetected
+}
+
+// detectConsensus runs all strategies and returns the cluster name detected by
+// at least quorum strategies. If more than one cluster name reaches the quorum
+// the one detected by the most strategies wins and a ConflictError is returned
+// on a tie. The other cluster names are returned as outvoted candidates of the
+// detection. The fields of the identity are combined from the agreeing
+// strategies with the first strategy taking precedence. The strategy of the
+// detection lists all agreeing strategies.
+func (c *ClusterNameFinder) detectConsensus(ctx context.Context, apiClient client.Client) (Detection, error) {
+	outcomes := make([]StrategyOutcome, 0, len(c.strategies))
+	var candidates []Candidate
+	identities := make(map[string]*Identity)
+	for _, strategy := range c.strategies {
+		identity, err := strategy.Detect(ctx, apiClient)
+		if err != nil && !c.continueOnError {
+			return Detection{}, err
+		}
+
+		if err != nil || identity.ClusterName == "" {
+			outcomes = append(outcomes, StrategyOutcome{
+				Strategy: strategy.Name(),
+				Err:      err,
+			})
+			continue
+		}
+
+		known, ok := identities[identity.ClusterName]
+		if !ok {
+			identities[identity.ClusterName] = &identity
+			candidates = append(candidates, Candidate{ClusterName: identity.ClusterName})
+		} else {
+			fillIdentity(known, identity)
+		}
+		for i := range candidates {
+			if candidates[i].ClusterName == identity.ClusterName {
+				candidates[i].Strategies = append(candidates[i].Strategies, strategy.Name())
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Detection{}, c.notDetected(outcomes)
+	}
+
+	winner, tie := -1, false
+	for i, candidate := range candidates {
+		if len(candidate.Strategies) < c.quorum {
+			continue
+		}
+		switch {
+		case winner == -1 || len(candidate.Strategies) > len(candidates[winner].Strategies):
+			winner, tie = i, false
+		case len(candidate.Strategies) == len(candidates[winner].Strategies):
+			tie = true
+		}
+	}
+	switch {
+	case winner == -1 && len(candidates) == 1:
+		return Detection{}, &QuorumError{Quorum: c.quorum, Candidate: candidates[0]}
+	case winner == -1 || tie:
+		return Detection{}, &ConflictError{Candidates: candidates}
+	}
+
+	candidate := candidates[winner]
+	detection, err := c.complete(ctx, apiClient, *identities[candidate.ClusterName], strings.Join(candidate.Strategies, ","))
+	if err != nil {
+		return Detection{}, err
+	}
+	for i, outvoted := range candidates {
+		if i != winner {
+			detection.Outvoted = append(detection.Outvoted, outvoted)
+		}
+	}
+	return detection, nil
+}
+
+// fillIdentity sets the fields of identity that are empty from other.
+func fillIdentity(identity *Identity, other Identity) {
+	known := identity.Data()
+	for key, value := range other.Data() {
+		if _, ok := known[key]; !ok {
+			identity.SetAttribute(key, value)
+		}
+	}
+}
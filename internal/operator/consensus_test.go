@@ -0,0 +1,166 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClusterNameFinderDetectConsensus(t *testing.T) {
+	var (
+		ctx = context.Background()
+	)
+
+	t.Run("Return cluster name when strategies agree", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{
+				newNamedFakeStrategy("first", "clusterName", nil),
+				newNamedFakeStrategy("second", "", nil),
+				newNamedFakeStrategy("third", "clusterName", nil)},
+			quorum: 2,
+		}
+		apiClient := fake.NewClientBuilder().Build()
+
+		detection, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Detection{
+			Identity: Identity{ClusterName: "clusterName"},
+			Strategy: "first,third",
+		}, detection)
+	})
+
+	t.Run("Return cluster name of the quorum when strategies disagree", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{
+				newNamedFakeStrategy("first", "clusterName", nil),
+				newNamedFakeStrategy("second", "other", nil),
+				newNamedFakeStrategy("third", "clusterName", nil)},
+			quorum: 2,
+		}
+		apiClient := fake.NewClientBuilder().Build()
+
+		detection, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Detection{
+			Identity: Identity{ClusterName: "clusterName"},
+			Strategy: "first,third",
+			Outvoted: []Candidate{
+				{ClusterName: "other", Strategies: []string{"second"}},
+			},
+		}, detection)
+	})
+
+	t.Run("Return cluster name detected by most strategies when several reach the quorum", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{
+				newNamedFakeStrategy("first", "other", nil),
+				newNamedFakeStrategy("second", "clusterName", nil),
+				newNamedFakeStrategy("third", "clusterName", nil)},
+			quorum: 1,
+		}
+		apiClient := fake.NewClientBuilder().Build()
+
+		detection, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "clusterName", detection.ClusterName)
+		assert.Equal(t, []Candidate{
+			{ClusterName: "other", Strategies: []string{"first"}},
+		}, detection.Outvoted)
+	})
+
+	t.Run("Return conflict when strategies disagree without a quorum", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{
+				newNamedFakeStrategy("first", "clusterName", nil),
+				newNamedFakeStrategy("second", "other", nil),
+				newNamedFakeStrategy("third", "clusterName", nil)},
+			quorum: 3,
+		}
+		apiClient := fake.NewClientBuilder().Build()
+
+		_, err := sut.Detect(ctx, apiClient)
+
+		assert.EqualError(t, err, "strategies disagree on the cluster name: 'clusterName' (first, third), 'other' (second)")
+		var conflictErr *ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		assert.Equal(t, []Candidate{
+			{ClusterName: "clusterName", Strategies: []string{"first", "third"}},
+			{ClusterName: "other", Strategies: []string{"second"}},
+		}, conflictErr.Candidates)
+		assert.NotErrorIs(t, err, ErrClusterNameNotDetected)
+	})
+
+	t.Run("Return conflict on a tie", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{
+				newNamedFakeStrategy("first", "clusterName", nil),
+				newNamedFakeStrategy("second", "other", nil)},
+			quorum: 1,
+		}
+		apiClient := fake.NewClientBuilder().Build()
+
+		_, err := sut.Detect(ctx, apiClient)
+
+		assert.EqualError(t, err, "strategies disagree on the cluster name: 'clusterName' (first), 'other' (second)")
+	})
+
+	t.Run("Return error when quorum is not reached", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{
+				newNamedFakeStrategy("first", "clusterName", nil),
+				newNamedFakeStrategy("second", "", nil)},
+			quorum: 2,
+		}
+		apiClient := fake.NewClientBuilder().Build()
+
+		_, err := sut.Detect(ctx, apiClient)
+
+		assert.EqualError(t, err, "could not detect cluster name: 'clusterName' (first) detected by 1 strategies but the quorum is 2")
+		assert.ErrorIs(t, err, ErrClusterNameNotDetected)
+	})
+
+	t.Run("Return error when no strategy detects a cluster name", func(t *testing.T) {
+		sut := &ClusterNameFinder{
+			strategies: []clusterNameStrategy{newNamedFakeStrategy("first", "", nil)},
+			quorum:     1,
+		}
+		apiClient := fake.NewClientBuilder().Build()
+
+		_, err := sut.Detect(ctx, apiClient)
+
+		assert.Equal(t, ErrClusterNameNotDetected, err)
+	})
+}
+
+func TestFillIdentity(t *testing.T) {
+	identity := Identity{
+		ClusterName:   "clusterName",
+		CloudProvider: "aws",
+	}
+
+	fillIdentity(&identity, Identity{
+		ClusterName:       "clusterName",
+		CloudProvider:     "gcp",
+		Region:            "eu-west-1",
+		KubernetesVersion: "v1.27.3",
+		Attributes: map[string]string{
+			"controlPlaneEndpoint": "api.lunar.tech:6443",
+		},
+	})
+
+	assert.Equal(t, Identity{
+		ClusterName:       "clusterName",
+		CloudProvider:     "aws",
+		Region:            "eu-west-1",
+		KubernetesVersion: "v1.27.3",
+		Attributes: map[string]string{
+			"controlPlaneEndpoint": "api.lunar.tech:6443",
+		},
+	}, identity)
+}
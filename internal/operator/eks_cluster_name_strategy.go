@@ -0,0 +1,153 @@
+package operator
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	eksctlClusterNameLabel = "alpha.eksctl.io/cluster-name"
+	eksNodeGroupLabel      = "eks.amazonaws.com/nodegroup"
+	eksComputeTypeLabel    = "eks.amazonaws.com/compute-type"
+
+	awsNodeNamespace      = "kube-system"
+	awsNodeDaemonSetName  = "aws-node"
+	awsNodeContainerName  = "aws-node"
+	awsNodeClusterNameEnv = "CLUSTER_NAME"
+
+	awsCloudProvider    = "aws"
+	awsProviderIDPrefix = "aws://"
+
+	topologyRegionLabel = "topology.kubernetes.io/region"
+	topologyZoneLabel   = "topology.kubernetes.io/zone"
+)
+
+// awsRegionPattern matches the region part of an availability zone, e.g.
+// eu-west-1 in eu-west-1a or us-west-2 in the local zone us-west-2-lax-1a.
+var awsRegionPattern = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]*)?-[a-z]+-[0-9]+`)
+
+// eksStrategy detects the cluster name on EKS from eksctl node labels or the
+// CLUSTER_NAME environment variable of the aws-node DaemonSet.
+type eksStrategy struct{}
+
+func (e *eksStrategy) Name() string {
+	return "eks"
+}
+
+func (e *eksStrategy) Detect(ctx context.Context, apiClient client.Client) (Identity, error) {
+	node, found, err := getEKSNode(ctx, apiClient)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if !found {
+		return Identity{}, nil
+	}
+
+	identity := awsNodeIdentity(node)
+	identity.ClusterName = node.Labels[eksctlClusterNameLabel]
+	if identity.ClusterName == "" {
+		identity.ClusterName, err = getAWSNodeClusterName(ctx, apiClient)
+		if err != nil {
+			return Identity{}, err
+		}
+	}
+
+	return identity, nil
+}
+
+// getEKSNode returns the first node that runs on AWS and carries EKS or eksctl
+// labels.
+func getEKSNode(ctx context.Context, apiClient client.Client) (corev1.Node, bool, error) {
+	var nodeList corev1.NodeList
+	err := apiClient.List(ctx, &nodeList)
+	if err != nil {
+		return corev1.Node{}, false, err
+	}
+
+	for _, node := range nodeList.Items {
+		if !strings.HasPrefix(node.Spec.ProviderID, awsProviderIDPrefix) {
+			continue
+		}
+		if isEKSNode(node) {
+			return node, true, nil
+		}
+	}
+
+	return corev1.Node{}, false, nil
+}
+
+func isEKSNode(node corev1.Node) bool {
+	for _, label := range []string{eksctlClusterNameLabel, eksNodeGroupLabel, eksComputeTypeLabel} {
+		if _, ok := node.Labels[label]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getAWSNodeClusterName returns the CLUSTER_NAME environment variable of the
+// aws-node DaemonSet installed by the Amazon VPC CNI plugin.
+func getAWSNodeClusterName(ctx context.Context, apiClient client.Client) (string, error) {
+	var daemonSet appsv1.DaemonSet
+	err := apiClient.Get(ctx, types.NamespacedName{
+		Namespace: awsNodeNamespace,
+		Name:      awsNodeDaemonSetName,
+	}, &daemonSet)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	for _, container := range daemonSet.Spec.Template.Spec.Containers {
+		if container.Name != awsNodeContainerName {
+			continue
+		}
+		for _, env := range container.Env {
+			if env.Name == awsNodeClusterNameEnv {
+				return env.Value, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// awsNodeIdentity returns the cloud provider and region of an AWS node. The
+// topology label is preferred and the region is derived from the zone in the
+// provider ID when it is missing. The zones are not set as they are read from
+// all nodes by the ClusterNameFinder. The account is not known as the provider
+// ID does not include it.
+func awsNodeIdentity(node corev1.Node) Identity {
+	identity := Identity{
+		CloudProvider: awsCloudProvider,
+		Region:        node.Labels[topologyRegionLabel],
+	}
+	if identity.Region == "" {
+		identity.Region = awsRegionPattern.FindString(awsZoneFromProviderID(node.Spec.ProviderID))
+	}
+
+	return identity
+}
+
+// awsZoneFromProviderID extracts the availability zone from an AWS provider ID
+// on the form aws:///<zone>/<instance-id>.
+func awsZoneFromProviderID(providerID string) string {
+	if !strings.HasPrefix(providerID, awsProviderIDPrefix) {
+		return ""
+	}
+
+	parts := strings.Split(strings.TrimPrefix(providerID, awsProviderIDPrefix), "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
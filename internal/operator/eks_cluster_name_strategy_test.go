@@ -0,0 +1,67 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestAWSNodeIdentity(t *testing.T) {
+	tt := []struct {
+		name       string
+		providerID string
+		expected   Identity
+	}{
+		{
+			name:       "regular zone",
+			providerID: "aws:///eu-west-1a/i-0123456789abcdef0",
+			expected: Identity{
+				CloudProvider: "aws",
+				Region:        "eu-west-1",
+			},
+		},
+		{
+			name:       "local zone",
+			providerID: "aws:///us-west-2-lax-1a/i-0123456789abcdef0",
+			expected: Identity{
+				CloudProvider: "aws",
+				Region:        "us-west-2",
+			},
+		},
+		{
+			name:       "govcloud zone",
+			providerID: "aws:///us-gov-west-1a/i-0123456789abcdef0",
+			expected: Identity{
+				CloudProvider: "aws",
+				Region:        "us-gov-west-1",
+			},
+		},
+		{
+			name:       "fargate",
+			providerID: "aws:///eu-west-1c/0123456789abcdef/fargate-ip-192-168-1-2.eu-west-1.compute.internal",
+			expected: Identity{
+				CloudProvider: "aws",
+				Region:        "eu-west-1",
+			},
+		},
+		{
+			name:       "malformed provider id",
+			providerID: "aws:///i-0123456789abcdef0",
+			expected: Identity{
+				CloudProvider: "aws",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			identity := awsNodeIdentity(corev1.Node{
+				Spec: corev1.NodeSpec{
+					ProviderID: tc.providerID,
+				},
+			})
+
+			assert.Equal(t, tc.expected, identity)
+		})
+	}
+}
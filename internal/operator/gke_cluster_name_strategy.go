@@ -0,0 +1,157 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	gkeNodePoolLabel       = "cloud.google.com/gke-nodepool"
+	gkeNodeNamePrefix      = "gke-"
+	gceProviderIDPrefix    = "gce://"
+	gcpCloudProvider       = "gcp"
+	gkeMetadataClusterPath = "/computeMetadata/v1/instance/attributes/cluster-name"
+	gkeMetadataTimeout     = 2 * time.Second
+)
+
+// gkeStrategy detects the cluster name on GKE. The cluster name is read from
+// the GCE metadata server when an endpoint is configured. It is derived from
+// the node name on the form gke-<cluster>-<node-pool>-<hash>-<suffix> when no
+// endpoint is configured or the metadata server does not return it.
+type gkeStrategy struct {
+	metadataEndpoint string
+	httpClient       *http.Client
+}
+
+func newGKEStrategy(metadataEndpoint string) *gkeStrategy {
+	return &gkeStrategy{
+		metadataEndpoint: strings.TrimSuffix(metadataEndpoint, "/"),
+		httpClient: &http.Client{
+			Timeout: gkeMetadataTimeout,
+		},
+	}
+}
+
+func (g *gkeStrategy) Name() string {
+	return "gke"
+}
+
+func (g *gkeStrategy) Detect(ctx context.Context, apiClient client.Client) (Identity, error) {
+	node, found, err := getGKENode(ctx, apiClient)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if !found {
+		return Identity{}, nil
+	}
+
+	identity := gceNodeIdentity(node)
+	if g.metadataEndpoint != "" {
+		identity.ClusterName, err = g.getMetadataClusterName(ctx)
+		if err != nil {
+			// the metadata server is optional. The cluster name is derived
+			// from the node name instead of failing the strategy.
+			log.FromContext(ctx).Error(err, "Failed to read cluster name from GCE metadata server, deriving it from the node name")
+		}
+	}
+	if identity.ClusterName == "" {
+		identity.ClusterName = gkeClusterNameFromNode(node)
+	}
+
+	return identity, nil
+}
+
+func getGKENode(ctx context.Context, apiClient client.Client) (corev1.Node, bool, error) {
+	var nodeList corev1.NodeList
+	err := apiClient.List(ctx, &nodeList, client.HasLabels{gkeNodePoolLabel})
+	if err != nil {
+		return corev1.Node{}, false, err
+	}
+
+	for _, node := range nodeList.Items {
+		if strings.HasPrefix(node.Spec.ProviderID, gceProviderIDPrefix) {
+			return node, true, nil
+		}
+	}
+
+	return corev1.Node{}, false, nil
+}
+
+// getMetadataClusterName reads the cluster-name instance attribute from the GCE
+// metadata server.
+func (g *gkeStrategy) getMetadataClusterName(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.metadataEndpoint+gkeMetadataClusterPath, nil)
+	if err != nil {
+		return "", fmt.Errorf("create metadata request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("query metadata server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("query metadata server: unexpected status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read metadata response: %w", err)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// gkeClusterNameFromNode derives the cluster name from the name of a GKE node.
+// An empty string is returned if the node name does not contain the node pool
+// name, e.g. because GKE truncated it.
+func gkeClusterNameFromNode(node corev1.Node) string {
+	nodePool := node.Labels[gkeNodePoolLabel]
+	if !strings.HasPrefix(node.Name, gkeNodeNamePrefix) || nodePool == "" {
+		return ""
+	}
+
+	name := strings.TrimPrefix(node.Name, gkeNodeNamePrefix)
+	index := strings.LastIndex(name, "-"+nodePool+"-")
+	if index <= 0 {
+		return ""
+	}
+	return name[:index]
+}
+
+// gceNodeIdentity returns the cloud provider, project, region and zone of a
+// GCE node from its provider ID on the form gce://<project>/<zone>/<instance>.
+func gceNodeIdentity(node corev1.Node) Identity {
+	identity := Identity{
+		CloudProvider: gcpCloudProvider,
+	}
+
+	parts := strings.Split(strings.TrimPrefix(node.Spec.ProviderID, gceProviderIDPrefix), "/")
+	if len(parts) != 3 {
+		return identity
+	}
+
+	project, zone := parts[0], parts[1]
+	identity.Project = project
+	if zone != "" {
+		identity.Zones = []string{zone}
+		// zones are on the form <region>-<letter>, e.g. europe-west1-b
+		if index := strings.LastIndex(zone, "-"); index > 0 {
+			identity.Region = zone[:index]
+		}
+	}
+	return identity
+}
@@ -0,0 +1,130 @@
+package operator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGKEStrategyDetect(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "gke-prod-default-pool-4b7f3c2a-x8kd",
+				Labels: map[string]string{
+					"cloud.google.com/gke-nodepool": "default-pool",
+				},
+			},
+			Spec: corev1.NodeSpec{
+				ProviderID: "gce://lunar-prod/europe-west1-b/gke-prod-default-pool-4b7f3c2a-x8kd",
+			},
+		}
+		expectedIdentity = Identity{
+			CloudProvider: "gcp",
+			Project:       "lunar-prod",
+			Region:        "europe-west1",
+			Zones:         []string{"europe-west1-b"},
+		}
+	)
+
+	t.Run("Return cluster name from metadata server", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Path != "/computeMetadata/v1/instance/attributes/cluster-name" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte("prod-from-metadata"))
+		}))
+		defer server.Close()
+		sut := newGKEStrategy(server.URL)
+		apiClient := fake.NewClientBuilder().WithObjects(node).Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		expected := expectedIdentity
+		expected.ClusterName = "prod-from-metadata"
+		assert.Equal(t, expected, identity)
+	})
+
+	t.Run("Return cluster name from node name when metadata server fails", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+		sut := newGKEStrategy(server.URL)
+		apiClient := fake.NewClientBuilder().WithObjects(node).Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		expected := expectedIdentity
+		expected.ClusterName = "prod"
+		assert.Equal(t, expected, identity)
+	})
+
+	t.Run("Return cluster name from node name when metadata server has no cluster name", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+		sut := newGKEStrategy(server.URL)
+		apiClient := fake.NewClientBuilder().WithObjects(node).Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		expected := expectedIdentity
+		expected.ClusterName = "prod"
+		assert.Equal(t, expected, identity)
+	})
+
+	t.Run("Return cluster name from node name when metadata server times out", func(t *testing.T) {
+		done := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer server.Close()
+		defer close(done)
+		sut := newGKEStrategy(server.URL)
+		sut.httpClient.Timeout = 10 * time.Millisecond
+		apiClient := fake.NewClientBuilder().WithObjects(node).Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		expected := expectedIdentity
+		expected.ClusterName = "prod"
+		assert.Equal(t, expected, identity)
+	})
+
+	t.Run("Return cluster name from node name without metadata server", func(t *testing.T) {
+		sut := newGKEStrategy("")
+		apiClient := fake.NewClientBuilder().WithObjects(node).Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		expected := expectedIdentity
+		expected.ClusterName = "prod"
+		assert.Equal(t, expected, identity)
+	})
+
+	t.Run("Return no cluster name without GKE nodes", func(t *testing.T) {
+		sut := newGKEStrategy("")
+		apiClient := fake.NewClientBuilder().Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Identity{}, identity)
+	})
+}
@@ -0,0 +1,124 @@
+package operator
+
+import (
+	"sort"
+	"strings"
+)
+
+// Keys of the identity fields in the managed ConfigMap.
+const (
+	ClusterNameKey       = "clusterName"
+	ClusterIDKey         = "clusterID"
+	CloudProviderKey     = "cloudProvider"
+	RegionKey            = "region"
+	ZonesKey             = "zones"
+	AccountKey           = "account"
+	ProjectKey           = "project"
+	EnvironmentKey       = "environment"
+	KubernetesVersionKey = "kubernetesVersion"
+)
+
+// Well-known keys of additional attributes reported by the strategies.
+const (
+	AttributeResourceGroup        = "resourceGroup"
+	AttributeControlPlaneEndpoint = "controlPlaneEndpoint"
+)
+
+// zonesSeparator separates the zones when they are written as a single value.
+const zonesSeparator = ","
+
+// Identity is the structured identity of a cluster. Only the cluster name is
+// required. The other fields are empty when they are unknown.
+type Identity struct {
+	ClusterName string
+	// ClusterID is the UID of the kube-system namespace. Unlike the cluster
+	// name it is unique and never changes for the lifetime of the cluster.
+	ClusterID string
+	// CloudProvider is the cloud the cluster runs in, i.e. aws, azure or gcp.
+	CloudProvider string
+	Region        string
+	// Zones are the availability zones the nodes of the cluster run in.
+	Zones []string
+	// Account is the cloud account the cluster runs in, e.g. the Azure
+	// subscription ID.
+	Account string
+	// Project is the GCP project the cluster runs in.
+	Project           string
+	Environment       string
+	KubernetesVersion string
+	// Attributes are additional attributes without a dedicated field.
+	Attributes map[string]string
+}
+
+// Data returns each known field of the identity as its own key. The fields
+// take precedence over attributes with the same key.
+func (i Identity) Data() map[string]string {
+	data := make(map[string]string, len(i.Attributes)+9)
+	for key, value := range i.Attributes {
+		data[key] = value
+	}
+	data[ClusterNameKey] = i.ClusterName
+	setValue(data, ClusterIDKey, i.ClusterID)
+	setValue(data, CloudProviderKey, i.CloudProvider)
+	setValue(data, RegionKey, i.Region)
+	setValue(data, ZonesKey, strings.Join(i.Zones, zonesSeparator))
+	setValue(data, AccountKey, i.Account)
+	setValue(data, ProjectKey, i.Project)
+	setValue(data, EnvironmentKey, i.Environment)
+	setValue(data, KubernetesVersionKey, i.KubernetesVersion)
+	return data
+}
+
+// SetAttribute sets the field matching key or adds an attribute if the key has
+// no dedicated field. Zones are given as a comma separated list. Empty values
+// are ignored. The cluster name and ID cannot be set as attributes.
+func (i *Identity) SetAttribute(key, value string) {
+	if value == "" {
+		return
+	}
+
+	switch key {
+	case ClusterNameKey, ClusterIDKey:
+	case CloudProviderKey:
+		i.CloudProvider = value
+	case RegionKey:
+		i.Region = value
+	case ZonesKey:
+		i.Zones = normalizeZones(strings.Split(value, zonesSeparator))
+	case AccountKey:
+		i.Account = value
+	case ProjectKey:
+		i.Project = value
+	case EnvironmentKey:
+		i.Environment = value
+	case KubernetesVersionKey:
+		i.KubernetesVersion = value
+	default:
+		if i.Attributes == nil {
+			i.Attributes = map[string]string{}
+		}
+		i.Attributes[key] = value
+	}
+}
+
+// normalizeZones returns the sorted unique non-empty zones.
+func normalizeZones(zones []string) []string {
+	seen := make(map[string]bool, len(zones))
+	var normalized []string
+	for _, zone := range zones {
+		zone = strings.TrimSpace(zone)
+		if zone == "" || seen[zone] {
+			continue
+		}
+		seen[zone] = true
+		normalized = append(normalized, zone)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func setValue(data map[string]string, key, value string) {
+	if value != "" {
+		data[key] = value
+	}
+}
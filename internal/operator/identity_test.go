@@ -0,0 +1,91 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityData(t *testing.T) {
+	tt := []struct {
+		name     string
+		identity Identity
+		expected map[string]string
+	}{
+		{
+			name:     "only cluster name",
+			identity: Identity{ClusterName: "prod"},
+			expected: map[string]string{
+				"clusterName": "prod",
+			},
+		},
+		{
+			name: "all fields",
+			identity: Identity{
+				ClusterName:       "prod",
+				ClusterID:         "6a9cbbb4-0b1e-4c6a-9f0e-2b7e1c3f4d5a",
+				CloudProvider:     "aws",
+				Region:            "eu-west-1",
+				Zones:             []string{"eu-west-1a", "eu-west-1b"},
+				Account:           "123456789012",
+				Project:           "lunar-prod",
+				Environment:       "prod",
+				KubernetesVersion: "v1.27.3",
+				Attributes: map[string]string{
+					"resourceGroup": "lunar-prod",
+				},
+			},
+			expected: map[string]string{
+				"clusterName":       "prod",
+				"clusterID":         "6a9cbbb4-0b1e-4c6a-9f0e-2b7e1c3f4d5a",
+				"cloudProvider":     "aws",
+				"region":            "eu-west-1",
+				"zones":             "eu-west-1a,eu-west-1b",
+				"account":           "123456789012",
+				"project":           "lunar-prod",
+				"environment":       "prod",
+				"kubernetesVersion": "v1.27.3",
+				"resourceGroup":     "lunar-prod",
+			},
+		},
+		{
+			name: "fields take precedence over attributes",
+			identity: Identity{
+				ClusterName: "prod",
+				Region:      "eu-west-1",
+				Attributes: map[string]string{
+					"clusterName": "other",
+					"region":      "other",
+				},
+			},
+			expected: map[string]string{
+				"clusterName": "prod",
+				"region":      "eu-west-1",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.identity.Data())
+		})
+	}
+}
+
+func TestIdentitySetAttribute(t *testing.T) {
+	identity := Identity{ClusterName: "prod"}
+
+	identity.SetAttribute("clusterName", "other")
+	identity.SetAttribute("environment", "prod")
+	identity.SetAttribute("zones", "eu-west-1b, eu-west-1a,eu-west-1b")
+	identity.SetAttribute("team", "platform")
+	identity.SetAttribute("empty", "")
+
+	assert.Equal(t, Identity{
+		ClusterName: "prod",
+		Environment: "prod",
+		Zones:       []string{"eu-west-1a", "eu-west-1b"},
+		Attributes: map[string]string{
+			"team": "platform",
+		},
+	}, identity)
+}
@@ -0,0 +1,136 @@
+package operator
+
+import (
+	"context"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	kopsInstanceGroupLabel   = "kops.k8s.io/instancegroup"
+	kopsKubernetesClusterTag = "KubernetesCluster"
+
+	clusterAutoscalerNamespace                   = "kube-system"
+	clusterAutoscalerName                        = "cluster-autoscaler"
+	clusterAutoscalerAutoDiscoveryArgumentPrefix = "--node-group-auto-discovery="
+	clusterAutoscalerTagPrefix                   = "k8s.io/cluster-autoscaler/"
+	clusterAutoscalerEnabledTag                  = clusterAutoscalerTagPrefix + "enabled"
+)
+
+// kopsStrategy detects the cluster name on kOps from the KubernetesCluster tag
+// surfaced as a node label and falls back to the auto discovery tags of the
+// cluster-autoscaler Deployment.
+type kopsStrategy struct{}
+
+func (k *kopsStrategy) Name() string {
+	return "kops"
+}
+
+func (k *kopsStrategy) Detect(ctx context.Context, apiClient client.Client) (Identity, error) {
+	node, found, err := getKopsNode(ctx, apiClient)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if !found {
+		return Identity{}, nil
+	}
+
+	identity := cloudNodeIdentity(node)
+	identity.ClusterName = node.Labels[kopsKubernetesClusterTag]
+	if identity.ClusterName == "" {
+		identity.ClusterName, err = getClusterAutoscalerClusterName(ctx, apiClient)
+		if err != nil {
+			return Identity{}, err
+		}
+	}
+
+	return identity, nil
+}
+
+func getKopsNode(ctx context.Context, apiClient client.Client) (corev1.Node, bool, error) {
+	var nodeList corev1.NodeList
+	err := apiClient.List(ctx, &nodeList, client.HasLabels{kopsInstanceGroupLabel})
+	if err != nil {
+		return corev1.Node{}, false, err
+	}
+
+	var kopsNode corev1.Node
+	found := false
+	for _, node := range nodeList.Items {
+		if node.Labels[kopsKubernetesClusterTag] != "" {
+			return node, true, nil
+		}
+		if !found {
+			kopsNode, found = node, true
+		}
+	}
+
+	return kopsNode, found, nil
+}
+
+// getClusterAutoscalerClusterName returns the cluster name from the
+// --node-group-auto-discovery argument of the cluster-autoscaler Deployment,
+// e.g. asg:tag=k8s.io/cluster-autoscaler/enabled,k8s.io/cluster-autoscaler/<name>.
+func getClusterAutoscalerClusterName(ctx context.Context, apiClient client.Client) (string, error) {
+	var deployment appsv1.Deployment
+	err := apiClient.Get(ctx, types.NamespacedName{
+		Namespace: clusterAutoscalerNamespace,
+		Name:      clusterAutoscalerName,
+	}, &deployment)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		args := append(append([]string{}, container.Command...), container.Args...)
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, clusterAutoscalerAutoDiscoveryArgumentPrefix) {
+				continue
+			}
+			clusterName := clusterNameFromAutoDiscovery(strings.TrimPrefix(arg, clusterAutoscalerAutoDiscoveryArgumentPrefix))
+			if clusterName != "" {
+				return clusterName, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func clusterNameFromAutoDiscovery(autoDiscovery string) string {
+	// strip the provider specific prefix, e.g. asg:tag=
+	if index := strings.Index(autoDiscovery, "="); index >= 0 && strings.Contains(autoDiscovery[:index], ":") {
+		autoDiscovery = autoDiscovery[index+1:]
+	}
+
+	for _, tag := range strings.Split(autoDiscovery, ",") {
+		// tags may be given as key=value
+		tag = strings.SplitN(tag, "=", 2)[0]
+		if tag == clusterAutoscalerEnabledTag || !strings.HasPrefix(tag, clusterAutoscalerTagPrefix) {
+			continue
+		}
+		return strings.TrimPrefix(tag, clusterAutoscalerTagPrefix)
+	}
+	return ""
+}
+
+// cloudNodeIdentity returns the cloud identity fields of a node based on the
+// cloud provider in its provider ID.
+func cloudNodeIdentity(node corev1.Node) Identity {
+	switch {
+	case strings.HasPrefix(node.Spec.ProviderID, awsProviderIDPrefix):
+		return awsNodeIdentity(node)
+	case strings.HasPrefix(node.Spec.ProviderID, gceProviderIDPrefix):
+		return gceNodeIdentity(node)
+	default:
+		return Identity{}
+	}
+}
@@ -0,0 +1,41 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterNameFromAutoDiscovery(t *testing.T) {
+	tt := []struct {
+		name          string
+		autoDiscovery string
+		expected      string
+	}{
+		{
+			name:          "asg tags",
+			autoDiscovery: "asg:tag=k8s.io/cluster-autoscaler/enabled,k8s.io/cluster-autoscaler/prod.k8s.lunar.tech",
+			expected:      "prod.k8s.lunar.tech",
+		},
+		{
+			name:          "asg tags with values",
+			autoDiscovery: "asg:tag=k8s.io/cluster-autoscaler/enabled=true,k8s.io/cluster-autoscaler/prod.k8s.lunar.tech=owned",
+			expected:      "prod.k8s.lunar.tech",
+		},
+		{
+			name:          "only enabled tag",
+			autoDiscovery: "asg:tag=k8s.io/cluster-autoscaler/enabled",
+			expected:      "",
+		},
+		{
+			name:          "other provider",
+			autoDiscovery: "mig:namePrefix=gke-prod,min=1,max=10",
+			expected:      "",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, clusterNameFromAutoDiscovery(tc.autoDiscovery))
+		})
+	}
+}
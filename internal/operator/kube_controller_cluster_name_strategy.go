@@ -16,17 +16,21 @@ const (
 
 type kubeControllerStrategy struct{}
 
-func (k *kubeControllerStrategy) GetClusterName(ctx context.Context, apiClient client.Client) (string, error) {
+func (k *kubeControllerStrategy) Name() string {
+	return "kubeController"
+}
+
+func (k *kubeControllerStrategy) Detect(ctx context.Context, apiClient client.Client) (Identity, error) {
 	pod, found, err := getKubeControllerManagerPod(ctx, apiClient)
 	if err != nil {
-		return "", err
+		return Identity{}, err
 	}
 
 	if !found {
-		return "", nil
+		return Identity{}, nil
 	}
 
-	return kubeControllerClusterNameFromPod(&pod), nil
+	return Identity{ClusterName: kubeControllerClusterNameFromPod(&pod)}, nil
 }
 
 func getKubeControllerManagerPod(ctx context.Context, apiClient client.Client) (corev1.Pod, bool, error) {
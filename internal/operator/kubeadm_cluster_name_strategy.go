@@ -0,0 +1,75 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	kubeadmConfigNamespace         = "kube-system"
+	kubeadmConfigName              = "kubeadm-config"
+	kubeadmClusterConfigurationKey = "ClusterConfiguration"
+	// kubeadmDefaultClusterName is the cluster name kubeadm uses when none is
+	// configured. It does not identify the cluster.
+	kubeadmDefaultClusterName = "kubernetes"
+)
+
+// kubeadmClusterConfiguration holds the fields of the kubeadm
+// ClusterConfiguration used to identify the cluster.
+type kubeadmClusterConfiguration struct {
+	ClusterName          string `json:"clusterName"`
+	KubernetesVersion    string `json:"kubernetesVersion"`
+	ControlPlaneEndpoint string `json:"controlPlaneEndpoint"`
+}
+
+// kubeadmStrategy detects the cluster name from the ClusterConfiguration stored
+// in the kubeadm-config ConfigMap by kubeadm. The strategy does not apply to
+// clusters using the kubeadm default cluster name.
+type kubeadmStrategy struct{}
+
+func (k *kubeadmStrategy) Name() string {
+	return "kubeadm"
+}
+
+func (k *kubeadmStrategy) Detect(ctx context.Context, apiClient client.Client) (Identity, error) {
+	var cm corev1.ConfigMap
+	err := apiClient.Get(ctx, types.NamespacedName{
+		Namespace: kubeadmConfigNamespace,
+		Name:      kubeadmConfigName,
+	}, &cm)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return Identity{}, nil
+		}
+		return Identity{}, err
+	}
+
+	document, ok := cm.Data[kubeadmClusterConfigurationKey]
+	if !ok {
+		return Identity{}, nil
+	}
+
+	var config kubeadmClusterConfiguration
+	err = yaml.Unmarshal([]byte(document), &config)
+	if err != nil {
+		return Identity{}, fmt.Errorf("parse %s in ConfigMap '%s/%s': %w", kubeadmClusterConfigurationKey, kubeadmConfigNamespace, kubeadmConfigName, err)
+	}
+
+	if config.ClusterName == kubeadmDefaultClusterName {
+		return Identity{}, nil
+	}
+
+	identity := Identity{
+		ClusterName:       config.ClusterName,
+		KubernetesVersion: config.KubernetesVersion,
+	}
+	identity.SetAttribute(AttributeControlPlaneEndpoint, config.ControlPlaneEndpoint)
+
+	return identity, nil
+}
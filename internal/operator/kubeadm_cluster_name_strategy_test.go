@@ -0,0 +1,75 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestKubeadmStrategyDetect(t *testing.T) {
+	var (
+		ctx           = context.Background()
+		kubeadmConfig = func(clusterConfiguration string) *corev1.ConfigMap {
+			return &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "kubeadm-config",
+					Namespace: "kube-system",
+				},
+				Data: map[string]string{
+					"ClusterConfiguration": clusterConfiguration,
+				},
+			}
+		}
+	)
+
+	t.Run("Return cluster name from ClusterConfiguration", func(t *testing.T) {
+		sut := &kubeadmStrategy{}
+		apiClient := fake.NewClientBuilder().WithObjects(kubeadmConfig(`
+apiVersion: kubeadm.k8s.io/v1beta3
+kind: ClusterConfiguration
+clusterName: prod
+kubernetesVersion: v1.27.2
+controlPlaneEndpoint: prod.lunar.tech:6443
+`)).Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Identity{
+			ClusterName:       "prod",
+			KubernetesVersion: "v1.27.2",
+			Attributes: map[string]string{
+				"controlPlaneEndpoint": "prod.lunar.tech:6443",
+			},
+		}, identity)
+	})
+
+	t.Run("Return no cluster name for the kubeadm default cluster name", func(t *testing.T) {
+		sut := &kubeadmStrategy{}
+		apiClient := fake.NewClientBuilder().WithObjects(kubeadmConfig(`
+apiVersion: kubeadm.k8s.io/v1beta3
+kind: ClusterConfiguration
+clusterName: kubernetes
+kubernetesVersion: v1.27.2
+`)).Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Identity{}, identity)
+	})
+
+	t.Run("Return no cluster name without kubeadm-config", func(t *testing.T) {
+		sut := &kubeadmStrategy{}
+		apiClient := fake.NewClientBuilder().Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Identity{}, identity)
+	})
+}
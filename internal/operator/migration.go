@@ -0,0 +1,188 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// MigratedToAnnotation records the name of the ConfigMap the data of a
+	// renamed managed ConfigMap was moved to.
+	MigratedToAnnotation = "config.lunar.tech/cluster-identity-migrated-to"
+	// MigratedAtAnnotation records when the data of a renamed managed
+	// ConfigMap was moved. The ConfigMap is deleted once the grace period has
+	// passed since then.
+	MigratedAtAnnotation = "config.lunar.tech/cluster-identity-migrated-at"
+)
+
+// migrationFieldOwner is the field manager of the keys moved from a renamed
+// ConfigMap. It differs from FieldOwner so the moved keys are not removed
+// when the identity is applied.
+var migrationFieldOwner = client.FieldOwner("cluster-identity-controller-migration")
+
+// MigrateConfigMaps moves the data of managed ConfigMaps in the namespace of nn
+// that are not named nn.Name to the ConfigMap nn. This happens when the name of
+// the managed ConfigMap is changed. Keys written by others and not present in
+// nn are copied and the old ConfigMaps are deleted once gracePeriod has passed
+// since the migration.
+//
+// The returned duration is the time until the next old ConfigMap should be
+// deleted. It is zero if there is nothing left to migrate.
+func MigrateConfigMaps(ctx context.Context, apiClient client.Client, nn types.NamespacedName, gracePeriod time.Duration, now time.Time) (time.Duration, error) {
+	var configMapList corev1.ConfigMapList
+	err := apiClient.List(ctx, &configMapList, client.InNamespace(nn.Namespace), client.MatchingLabelsSelector{Selector: ManagedConfigMapSelector()})
+	if err != nil {
+		return 0, fmt.Errorf("list managed ConfigMaps in namespace '%s': %w", nn.Namespace, err)
+	}
+
+	configMaps := make([]*corev1.ConfigMap, 0, len(configMapList.Items))
+	for i := range configMapList.Items {
+		configMaps = append(configMaps, &configMapList.Items[i])
+	}
+	return migrateRenamed(ctx, apiClient, "ConfigMap", configMaps, nn, gracePeriod, now, func(old *corev1.ConfigMap) error {
+		return moveKeys(ctx, apiClient, old, nn)
+	})
+}
+
+// migrateRenamed migrates the managed objects of the kind that are not named
+// nn.Name. Objects not yet marked as migrated are passed to migrate, if not
+// nil, and marked as migrated to nn.Name. They are deleted once gracePeriod
+// has passed since then.
+//
+// The returned duration is the time until the next old object should be
+// deleted. It is zero if there is nothing left to migrate.
+func migrateRenamed[T client.Object](ctx context.Context, apiClient client.Client, kind string, objects []T, nn types.NamespacedName, gracePeriod time.Duration, now time.Time, migrate func(old T) error) (time.Duration, error) {
+	var requeueAfter time.Duration
+	for _, old := range objects {
+		if old.GetName() == nn.Name {
+			continue
+		}
+
+		migratedAt, err := time.Parse(time.RFC3339, old.GetAnnotations()[MigratedAtAnnotation])
+		if err != nil {
+			if migrate != nil {
+				err = migrate(old)
+				if err != nil {
+					return 0, err
+				}
+			}
+			err = markMigrated(ctx, apiClient, kind, old, nn.Name, now)
+			if err != nil {
+				return 0, err
+			}
+			migratedAt = now
+		}
+
+		remaining := migratedAt.Add(gracePeriod).Sub(now)
+		if remaining > 0 {
+			if requeueAfter == 0 || remaining < requeueAfter {
+				requeueAfter = remaining
+			}
+			continue
+		}
+
+		err = deleteManaged(ctx, apiClient, kind, old)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return requeueAfter, nil
+}
+
+// markMigrated annotates old with the name it was migrated to and the time of
+// the migration.
+func markMigrated(ctx context.Context, apiClient client.Client, kind string, old client.Object, name string, now time.Time) error {
+	patch := client.MergeFrom(old.DeepCopyObject().(client.Object))
+	annotations := old.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[MigratedToAnnotation] = name
+	annotations[MigratedAtAnnotation] = now.UTC().Format(time.RFC3339)
+	old.SetAnnotations(annotations)
+	err := apiClient.Patch(ctx, old, patch)
+	if err != nil {
+		return fmt.Errorf("mark %s '%s/%s' as migrated: %w", kind, old.GetNamespace(), old.GetName(), err)
+	}
+	return nil
+}
+
+// moveKeys copies the keys of old missing in nn to nn.
+func moveKeys(ctx context.Context, apiClient client.Client, old *corev1.ConfigMap, nn types.NamespacedName) error {
+	var current corev1.ConfigMap
+	err := apiClient.Get(ctx, nn, &current)
+	if err != nil {
+		return fmt.Errorf("get ConfigMap '%s': %w", nn, err)
+	}
+
+	// keys applied by the operator are not moved as they are written to nn
+	// with the current key prefix and mapping
+	applied := appliedKeys(old, string(FieldOwner))
+	missing := map[string]string{}
+	for key, value := range old.Data {
+		if _, ok := current.Data[key]; !ok && !applied[key] {
+			missing[key] = value
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("Moving %d keys from ConfigMap '%s/%s' to '%s'", len(missing), old.Namespace, old.Name, nn.Name))
+	err = apiClient.Patch(ctx, &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nn.Name,
+			Namespace: nn.Namespace,
+		},
+		Data: missing,
+	}, client.Apply, migrationFieldOwner)
+	if err != nil {
+		return fmt.Errorf("apply ConfigMap '%s': %w", nn, err)
+	}
+	return nil
+}
+
+// appliedKeys returns the data keys of the ConfigMap owned by the field manager
+// through server-side apply.
+func appliedKeys(cm *corev1.ConfigMap, manager string) map[string]bool {
+	return ownedDataKeys(cm, func(entry metav1.ManagedFieldsEntry) bool {
+		return entry.Manager == manager && entry.Operation == metav1.ManagedFieldsOperationApply
+	})
+}
+
+// ownedDataKeys returns the data keys of the ConfigMap or Secret owned by the
+// managed fields entries that owns returns true for.
+func ownedDataKeys(obj client.Object, owns func(entry metav1.ManagedFieldsEntry) bool) map[string]bool {
+	keys := map[string]bool{}
+	for _, entry := range obj.GetManagedFields() {
+		if !owns(entry) || entry.FieldsV1 == nil {
+			continue
+		}
+		var fields struct {
+			Data map[string]json.RawMessage `json:"f:data"`
+		}
+		err := json.Unmarshal(entry.FieldsV1.Raw, &fields)
+		if err != nil {
+			continue
+		}
+		for field := range fields.Data {
+			if strings.HasPrefix(field, "f:") {
+				keys[strings.TrimPrefix(field, "f:")] = true
+			}
+		}
+	}
+	return keys
+}
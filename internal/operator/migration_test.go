@@ -0,0 +1,117 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAppliedKeys(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{
+					Manager:   "cluster-identity-controller",
+					Operation: metav1.ManagedFieldsOperationApply,
+					FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:clusterName":{},"f:region":{}},"f:metadata":{"f:labels":{"f:app.kubernetes.io/managed-by":{}}}}`)},
+				},
+				{
+					Manager:   "kubectl",
+					Operation: metav1.ManagedFieldsOperationUpdate,
+					FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:otherField":{}}}`)},
+				},
+			},
+		},
+	}
+
+	keys := appliedKeys(cm, "cluster-identity-controller")
+
+	assert.Equal(t, map[string]bool{"clusterName": true, "region": true}, keys)
+}
+
+func TestMigrateRenamed(t *testing.T) {
+	var (
+		ctx         = context.Background()
+		now         = time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+		gracePeriod = time.Hour
+		nn          = types.NamespacedName{Namespace: "payments", Name: "cluster-identity"}
+		secret      = func(name string, annotations map[string]string) *corev1.Secret {
+			return &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   "payments",
+					Labels:      map[string]string{ManagedByLabel: ManagedByValue},
+					Annotations: annotations,
+				},
+			}
+		}
+		get = func(t *testing.T, apiClient client.Client, name string) (*corev1.Secret, error) {
+			t.Helper()
+			var secret corev1.Secret
+			err := apiClient.Get(ctx, types.NamespacedName{Namespace: "payments", Name: name}, &secret)
+			return &secret, err
+		}
+	)
+
+	t.Run("Mark renamed object as migrated", func(t *testing.T) {
+		apiClient := fake.NewClientBuilder().WithObjects(secret("cluster-identity", nil), secret("old", nil)).Build()
+		current, err := get(t, apiClient, "cluster-identity")
+		require.NoError(t, err)
+		old, err := get(t, apiClient, "old")
+		require.NoError(t, err)
+
+		requeueAfter, err := migrateRenamed(ctx, apiClient, "Secret", []*corev1.Secret{current, old}, nn, gracePeriod, now, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, gracePeriod, requeueAfter)
+		old, err = get(t, apiClient, "old")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			MigratedToAnnotation: "cluster-identity",
+			MigratedAtAnnotation: "2023-06-01T12:00:00Z",
+		}, old.Annotations)
+	})
+
+	t.Run("Delete renamed object after grace period", func(t *testing.T) {
+		apiClient := fake.NewClientBuilder().WithObjects(secret("old", map[string]string{
+			MigratedToAnnotation: "cluster-identity",
+			MigratedAtAnnotation: "2023-06-01T10:00:00Z",
+		})).Build()
+		old, err := get(t, apiClient, "old")
+		require.NoError(t, err)
+
+		requeueAfter, err := migrateRenamed(ctx, apiClient, "Secret", []*corev1.Secret{old}, nn, gracePeriod, now, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), requeueAfter)
+		_, err = get(t, apiClient, "old")
+		assert.True(t, apierrors.IsNotFound(err), "expected old Secret to be deleted: %v", err)
+	})
+
+	t.Run("Keep renamed object changed since it was read", func(t *testing.T) {
+		apiClient := fake.NewClientBuilder().WithObjects(secret("old", map[string]string{
+			MigratedToAnnotation: "cluster-identity",
+			MigratedAtAnnotation: "2023-06-01T10:00:00Z",
+		})).Build()
+		old, err := get(t, apiClient, "old")
+		require.NoError(t, err)
+		changed := old.DeepCopy()
+		changed.Data = map[string][]byte{"clusterName": []byte("prod")}
+		require.NoError(t, apiClient.Update(ctx, changed))
+
+		_, err = migrateRenamed(ctx, apiClient, "Secret", []*corev1.Secret{old}, nn, gracePeriod, now, nil)
+
+		assert.True(t, apierrors.IsConflict(err), "expected conflict: %v", err)
+		_, err = get(t, apiClient, "old")
+		assert.NoError(t, err)
+	})
+}
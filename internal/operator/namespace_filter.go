@@ -0,0 +1,75 @@
+package operator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// NamespaceFilter decides which namespaces the cluster identity is injected
+// into. The zero value only accepts namespaces with the injection annotation.
+type NamespaceFilter struct {
+	// Selector selects namespaces by label in addition to the injection
+	// annotation. No namespaces are selected by label when nil.
+	Selector labels.Selector
+	// Exclude are glob patterns, e.g. kube-*, of namespace names that are
+	// never injected.
+	Exclude []string
+}
+
+// NewNamespaceFilter returns a NamespaceFilter from a label selector in the
+// full selector syntax and a comma separated list of excluded namespace
+// patterns. Both may be empty.
+func NewNamespaceFilter(selector, exclude string) (NamespaceFilter, error) {
+	var filter NamespaceFilter
+	if selector != "" {
+		parsed, err := labels.Parse(selector)
+		if err != nil {
+			return NamespaceFilter{}, fmt.Errorf("parse namespace selector '%s': %w", selector, err)
+		}
+		filter.Selector = parsed
+	}
+	for _, pattern := range strings.Split(exclude, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		// path.Match only reports malformed patterns when matching
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return NamespaceFilter{}, fmt.Errorf("parse excluded namespace pattern '%s': %w", pattern, err)
+		}
+		filter.Exclude = append(filter.Exclude, pattern)
+	}
+	return filter, nil
+}
+
+// IsNamespaceInjectable returns true if the namespace has the injection
+// annotation set to true or matches the selector, and is not excluded. Setting
+// the annotation to false opts a namespace out even if it matches the
+// selector.
+func (f NamespaceFilter) IsNamespaceInjectable(namespace corev1.Namespace) bool {
+	if f.isExcluded(namespace.Name) {
+		return false
+	}
+
+	switch namespace.Annotations[InjectionAnnotation] {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return f.Selector != nil && f.Selector.Matches(labels.Set(namespace.Labels))
+}
+
+func (f NamespaceFilter) isExcluded(name string) bool {
+	for _, pattern := range f.Exclude {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNamespaceFilterIsNamespaceInjectable(t *testing.T) {
+	filter, err := NewNamespaceFilter("team", "kube-*, default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name       string
+		namespace  corev1.Namespace
+		injectable bool
+	}{
+		{
+			name:       "inject annotated namespace",
+			namespace:  namespace("payments", map[string]string{InjectionAnnotation: "true"}, nil),
+			injectable: true,
+		},
+		{
+			name:       "inject namespace matching selector",
+			namespace:  namespace("payments", nil, map[string]string{"team": "payments"}),
+			injectable: true,
+		},
+		{
+			name:       "skip namespace not matching selector",
+			namespace:  namespace("payments", nil, map[string]string{"owner": "payments"}),
+			injectable: false,
+		},
+		{
+			name:       "skip namespace opting out with annotation",
+			namespace:  namespace("payments", map[string]string{InjectionAnnotation: "false"}, map[string]string{"team": "payments"}),
+			injectable: false,
+		},
+		{
+			name:       "skip excluded namespace matching selector",
+			namespace:  namespace("kube-public", nil, map[string]string{"team": "platform"}),
+			injectable: false,
+		},
+		{
+			name:       "skip excluded annotated namespace",
+			namespace:  namespace("default", map[string]string{InjectionAnnotation: "true"}, nil),
+			injectable: false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.injectable, filter.IsNamespaceInjectable(tc.namespace))
+		})
+	}
+
+	t.Run("only inject annotated namespaces by default", func(t *testing.T) {
+		assert.False(t, NamespaceFilter{}.IsNamespaceInjectable(namespace("payments", nil, map[string]string{"team": "payments"})))
+		assert.True(t, NamespaceFilter{}.IsNamespaceInjectable(namespace("payments", map[string]string{InjectionAnnotation: "true"}, nil)))
+	})
+}
+
+func TestNewNamespaceFilter(t *testing.T) {
+	t.Run("Reject invalid selector", func(t *testing.T) {
+		_, err := NewNamespaceFilter("team in (", "")
+
+		assert.ErrorContains(t, err, "parse namespace selector 'team in ('")
+	})
+
+	t.Run("Reject invalid exclude pattern", func(t *testing.T) {
+		_, err := NewNamespaceFilter("", "kube-[")
+
+		assert.EqualError(t, err, "parse excluded namespace pattern 'kube-[': syntax error in pattern")
+	})
+}
+
+func namespace(name string, annotations, labels map[string]string) corev1.Namespace {
+	return corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: annotations,
+			Labels:      labels,
+		},
+	}
+}
@@ -13,18 +13,22 @@ const (
 
 type nodeLabelStrategy struct{}
 
-func (k *nodeLabelStrategy) GetClusterName(ctx context.Context, apiClient client.Client) (string, error) {
+func (k *nodeLabelStrategy) Name() string {
+	return "nodeLabel"
+}
+
+func (k *nodeLabelStrategy) Detect(ctx context.Context, apiClient client.Client) (Identity, error) {
 
 	node, found, err := getNodeWithClusterNameLabel(ctx, apiClient)
 	if err != nil {
-		return "", err
+		return Identity{}, err
 	}
 
 	if !found {
-		return "", nil
+		return Identity{}, nil
 	}
 
-	return node.Labels[nodeLabel], nil
+	return Identity{ClusterName: node.Labels[nodeLabel]}, nil
 }
 
 func getNodeWithClusterNameLabel(ctx context.Context, apiClient client.Client) (corev1.Node, bool, error) {
@@ -0,0 +1,122 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	openShiftInfrastructureName = "cluster"
+
+	AttributeInfrastructureName = "infrastructureName"
+	AttributePlatform           = "platform"
+	AttributeAPIServerURL       = "apiServerURL"
+)
+
+var openShiftInfrastructureGVK = schema.GroupVersionKind{
+	Group:   "config.openshift.io",
+	Version: "v1",
+	Kind:    "Infrastructure",
+}
+
+// openShiftStrategy detects the cluster name from the Infrastructure object
+// named cluster on OpenShift. The cluster name is read from the API server URL
+// on the form https://api.<cluster-name>.<base-domain>:6443 and falls back to
+// the infrastructure name.
+type openShiftStrategy struct{}
+
+func (o *openShiftStrategy) Name() string {
+	return "openshift"
+}
+
+func (o *openShiftStrategy) Detect(ctx context.Context, apiClient client.Client) (Identity, error) {
+	infrastructure := &unstructured.Unstructured{}
+	infrastructure.SetGroupVersionKind(openShiftInfrastructureGVK)
+	err := apiClient.Get(ctx, types.NamespacedName{Name: openShiftInfrastructureName}, infrastructure)
+	if err != nil {
+		// the Infrastructure CRD is only installed on OpenShift
+		if apierrors.IsNotFound(err) || isAPINotInstalled(err) {
+			return Identity{}, nil
+		}
+		return Identity{}, fmt.Errorf("get OpenShift Infrastructure '%s': %w", openShiftInfrastructureName, err)
+	}
+
+	infrastructureName := nestedString(infrastructure, "status", "infrastructureName")
+	apiServerURL := nestedString(infrastructure, "status", "apiServerURL")
+	platform := nestedString(infrastructure, "status", "platformStatus", "type")
+
+	identity := Identity{
+		ClusterName: openShiftClusterNameFromAPIServerURL(apiServerURL),
+	}
+	if identity.ClusterName == "" {
+		identity.ClusterName = infrastructureName
+	}
+	identity.SetAttribute(AttributeInfrastructureName, infrastructureName)
+	identity.SetAttribute(AttributeAPIServerURL, apiServerURL)
+	identity.SetAttribute(AttributePlatform, platform)
+	switch platform {
+	case "AWS":
+		identity.CloudProvider = awsCloudProvider
+		identity.Region = nestedString(infrastructure, "status", "platformStatus", "aws", "region")
+	case "GCP":
+		identity.CloudProvider = gcpCloudProvider
+		identity.Region = nestedString(infrastructure, "status", "platformStatus", "gcp", "region")
+		identity.Project = nestedString(infrastructure, "status", "platformStatus", "gcp", "projectID")
+	case "Azure":
+		identity.CloudProvider = azureCloudProvider
+		identity.SetAttribute(AttributeResourceGroup, nestedString(infrastructure, "status", "platformStatus", "azure", "resourceGroupName"))
+	}
+
+	return identity, nil
+}
+
+// openShiftClusterNameFromAPIServerURL returns the cluster name from an API
+// server URL on the form https://api.<cluster-name>.<base-domain>:6443.
+func openShiftClusterNameFromAPIServerURL(apiServerURL string) string {
+	u, err := url.Parse(apiServerURL)
+	if err != nil {
+		return ""
+	}
+
+	labels := strings.Split(u.Hostname(), ".")
+	if len(labels) < 3 || labels[0] != "api" {
+		return ""
+	}
+	return labels[1]
+}
+
+func nestedString(obj *unstructured.Unstructured, fields ...string) string {
+	value, _, _ := unstructured.NestedString(obj.Object, fields...)
+	return value
+}
+
+// isAPINotInstalled returns true if the error is returned because the API group
+// of a custom resource is not served. Depending on the REST mapper this is a
+// no match error or a failed discovery of the group version where the server
+// returned not found.
+func isAPINotInstalled(err error) bool {
+	if meta.IsNoMatchError(err) {
+		return true
+	}
+	var discoveryErr *discovery.ErrGroupDiscoveryFailed
+	if !errors.As(err, &discoveryErr) || len(discoveryErr.Groups) == 0 {
+		return false
+	}
+	for _, groupErr := range discoveryErr.Groups {
+		if !apierrors.IsNotFound(groupErr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestOpenShiftStrategyDetect(t *testing.T) {
+	var (
+		ctx = context.Background()
+	)
+
+	t.Run("Return no cluster name when Infrastructure CRD is not installed", func(t *testing.T) {
+		sut := &openShiftStrategy{}
+		apiClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, client client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				return &meta.NoKindMatchError{GroupKind: openShiftInfrastructureGVK.GroupKind()}
+			},
+		}).Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Identity{}, identity)
+	})
+
+	t.Run("Return no cluster name when Infrastructure API group is not served", func(t *testing.T) {
+		sut := &openShiftStrategy{}
+		apiClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, client client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				// the error returned by the dynamic REST mapper of
+				// controller-runtime v0.15.0
+				return fmt.Errorf("failed to get API group resources: %w", &discovery.ErrGroupDiscoveryFailed{
+					Groups: map[schema.GroupVersion]error{
+						openShiftInfrastructureGVK.GroupVersion(): apierrors.NewNotFound(schema.GroupResource{}, ""),
+					},
+				})
+			},
+		}).Build()
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Identity{}, identity)
+	})
+
+	t.Run("Return no cluster name from API server without Infrastructure CRD", func(t *testing.T) {
+		sut := &openShiftStrategy{}
+		apiClient := startTestEnv(t)
+
+		identity, err := sut.Detect(ctx, apiClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Identity{}, identity)
+	})
+
+	t.Run("Return error when Infrastructure API group discovery fails", func(t *testing.T) {
+		sut := &openShiftStrategy{}
+		apiClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, client client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				return &discovery.ErrGroupDiscoveryFailed{
+					Groups: map[schema.GroupVersion]error{
+						openShiftInfrastructureGVK.GroupVersion(): fmt.Errorf("service unavailable"),
+					},
+				}
+			},
+		}).Build()
+
+		_, err := sut.Detect(ctx, apiClient)
+
+		assert.EqualError(t, err, "get OpenShift Infrastructure 'cluster': unable to retrieve the complete list of server APIs: config.openshift.io/v1: service unavailable")
+	})
+
+	t.Run("Return error when Infrastructure cannot be read", func(t *testing.T) {
+		sut := &openShiftStrategy{}
+		apiClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, client client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				return fmt.Errorf("forbidden")
+			},
+		}).Build()
+
+		_, err := sut.Detect(ctx, apiClient)
+
+		assert.EqualError(t, err, "get OpenShift Infrastructure 'cluster': forbidden")
+	})
+}
+
+func TestOpenShiftClusterNameFromAPIServerURL(t *testing.T) {
+	tt := []struct {
+		name         string
+		apiServerURL string
+		expected     string
+	}{
+		{
+			name:         "api server url",
+			apiServerURL: "https://api.prod.openshift.lunar.tech:6443",
+			expected:     "prod",
+		},
+		{
+			name:         "unexpected host",
+			apiServerURL: "https://prod.openshift.lunar.tech:6443",
+			expected:     "",
+		},
+		{
+			name:         "empty",
+			apiServerURL: "",
+			expected:     "",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, openShiftClusterNameFromAPIServerURL(tc.apiServerURL))
+		})
+	}
+}
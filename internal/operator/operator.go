@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -15,8 +18,34 @@ import (
 
 const (
 	InjectionAnnotation = "config.lunar.tech/cluster-identity-inject"
+
+	// ManagedByLabel marks the ConfigMaps and Secrets managed by the
+	// operator. Only ConfigMaps and Secrets with the label are watched.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "cluster-identity-controller"
+
+	// StrategyAnnotation records the strategy that detected the cluster name
+	// written to a managed ConfigMap.
+	StrategyAnnotation = "config.lunar.tech/cluster-identity-strategy"
+	// DetectionTimeAnnotation records the time the identity written to a
+	// managed ConfigMap was last detected.
+	DetectionTimeAnnotation = "config.lunar.tech/cluster-identity-detection-time"
 )
 
+// FieldOwner is the field manager used for the managed ConfigMaps and Secrets.
+var FieldOwner = client.FieldOwner("cluster-identity-controller")
+
+// ManagedConfigMapSelector selects the ConfigMaps and Secrets managed by the
+// operator.
+func ManagedConfigMapSelector() labels.Selector {
+	return labels.SelectorFromSet(labels.Set{ManagedByLabel: ManagedByValue})
+}
+
+// IsManaged returns true if the ConfigMap or Secret is managed by the operator.
+func IsManaged(obj client.Object) bool {
+	return obj.GetLabels()[ManagedByLabel] == ManagedByValue
+}
+
 func IsKubeControllerPod(podName string) bool {
 	return strings.HasPrefix(podName, "kube-controller-manager")
 }
@@ -30,56 +59,122 @@ func find(needle string, stack []string) string {
 	return ""
 }
 
-func IsNamespaceInjectable(namespace corev1.Namespace) bool {
-	return namespace.Annotations[InjectionAnnotation] == "true"
+// ConfigMapSource describes where the data of a managed ConfigMap or Secret
+// comes from. It is recorded in the metadata of the ConfigMap or Secret.
+type ConfigMapSource struct {
+	// Strategy is the strategy that detected the cluster name.
+	Strategy string
+	// DetectionTime is the time the identity was last detected.
+	DetectionTime time.Time
 }
 
-func CreateOrUpdateConfigMap(ctx context.Context, apiClient client.Client, nn types.NamespacedName, clusterName string) error {
-	var cm corev1.ConfigMap
-	err := apiClient.Get(ctx, nn, &cm)
+// CreateOrUpdateConfigMap applies the data to the ConfigMap with server-side
+// apply. The operator only owns the keys it writes so keys written by others
+// are left untouched. The ConfigMap is labelled as managed, so callers must not
+// apply to an existing ConfigMap that is not managed, see IsManaged.
+func CreateOrUpdateConfigMap(ctx context.Context, apiClient client.Client, nn types.NamespacedName, data map[string]string, source ConfigMapSource) error {
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nn.Name,
+			Namespace: nn.Namespace,
+		},
+		Data: data,
+	}
+	setManagedMetadata(cm, source)
+	setDetectionTime(cm, source.DetectionTime)
+
+	log.FromContext(ctx).Info(fmt.Sprintf("Applying ConfigMap '%s' with %d keys", nn.String(), len(data)))
+	err := apiClient.Patch(ctx, cm, client.Apply, client.ForceOwnership, FieldOwner)
 	if err != nil {
-		if !apierrors.IsNotFound(err) {
-			return fmt.Errorf("get ConfigMap '%s': %w", nn, err)
-		}
+		return fmt.Errorf("apply ConfigMap '%s': %w", nn, err)
+	}
+	return nil
+}
+
+// DeleteManagedConfigMaps removes the ConfigMaps managed by the operator in
+// the namespace, see removeManaged. ConfigMaps without the managed label are
+// left untouched as they were not created by the operator.
+func DeleteManagedConfigMaps(ctx context.Context, apiClient client.Client, namespace string) error {
+	var configMapList corev1.ConfigMapList
+	err := apiClient.List(ctx, &configMapList, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: ManagedConfigMapSelector()})
+	if err != nil {
+		return fmt.Errorf("list managed ConfigMaps in namespace '%s': %w", namespace, err)
+	}
 
-		err := createConfigMap(ctx, apiClient, nn, clusterName)
+	for i := range configMapList.Items {
+		err = removeManaged(ctx, apiClient, "ConfigMap", &configMapList.Items[i])
 		if err != nil {
-			return fmt.Errorf("create configmap: %w", err)
+			return err
 		}
-		return nil
 	}
+	return nil
+}
 
-	err = updateConfigMap(ctx, apiClient, cm, clusterName)
-	if err != nil {
-		return fmt.Errorf("update configmap: %w", err)
+// removeManaged removes a managed ConfigMap or Secret of the kind. The object
+// is deleted unless other field managers own some of its data keys. In that
+// case an empty object is applied instead so server-side apply only removes
+// the keys, labels and annotations owned by the operator.
+func removeManaged(ctx context.Context, apiClient client.Client, kind string, obj client.Object) error {
+	foreign := ownedDataKeys(obj, func(entry metav1.ManagedFieldsEntry) bool {
+		return entry.Manager != string(FieldOwner)
+	})
+	if len(foreign) == 0 {
+		return deleteManaged(ctx, apiClient, kind, obj)
 	}
 
+	log.FromContext(ctx).Info(fmt.Sprintf("Removing the keys of the operator from %s '%s/%s' as %d keys are owned by others", kind, obj.GetNamespace(), obj.GetName(), len(foreign)))
+	var empty unstructured.Unstructured
+	empty.SetAPIVersion("v1")
+	empty.SetKind(kind)
+	empty.SetNamespace(obj.GetNamespace())
+	empty.SetName(obj.GetName())
+	err := apiClient.Patch(ctx, &empty, client.Apply, FieldOwner)
+	if err != nil {
+		return fmt.Errorf("apply empty %s '%s/%s': %w", kind, obj.GetNamespace(), obj.GetName(), err)
+	}
 	return nil
 }
 
-func createConfigMap(ctx context.Context, apiClient client.Client, nn types.NamespacedName, clusterName string) error {
-	log.FromContext(ctx).Info(fmt.Sprintf("Creating ConfigMap '%s' with clusterName '%s'", nn.String(), clusterName))
+// deleteManaged deletes a managed ConfigMap or Secret of the kind. The delete
+// is rejected if the object was replaced or changed since it was read.
+func deleteManaged(ctx context.Context, apiClient client.Client, kind string, obj client.Object) error {
+	log.FromContext(ctx).Info(fmt.Sprintf("Deleting %s '%s/%s'", kind, obj.GetNamespace(), obj.GetName()))
+	uid := obj.GetUID()
+	resourceVersion := obj.GetResourceVersion()
+	err := apiClient.Delete(ctx, obj, client.Preconditions{UID: &uid, ResourceVersion: &resourceVersion})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete %s '%s/%s': %w", kind, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
 
-	return apiClient.Create(ctx, &corev1.ConfigMap{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "ConfigMap",
-			APIVersion: "v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:        "cluster-identity",
-			Namespace:   nn.Namespace,
-			Labels:      nil,
-			Annotations: nil,
-		},
-		Data: map[string]string{
-			"clusterName": clusterName,
-		},
+// setManagedMetadata sets the labels and annotations marking the object as
+// managed by the operator. No owner reference is set as deleting the owner
+// would garbage collect the objects in every injected namespace. The managed
+// label is used to find the objects instead.
+func setManagedMetadata(obj client.Object, source ConfigMapSource) {
+	obj.SetLabels(map[string]string{
+		ManagedByLabel: ManagedByValue,
 	})
+	if source.Strategy != "" {
+		obj.SetAnnotations(map[string]string{
+			StrategyAnnotation: source.Strategy,
+		})
+	}
 }
 
-func updateConfigMap(ctx context.Context, apiClient client.Client, cm corev1.ConfigMap, clusterName string) error {
-	log.FromContext(ctx).Info(fmt.Sprintf("Updating ConfigMap '%s/%s' with clusterName '%s'", cm.ObjectMeta.Namespace, cm.ObjectMeta.Name, clusterName))
-	cm.Data["clusterName"] = clusterName
-
-	return apiClient.Update(ctx, &cm)
+func setDetectionTime(obj client.Object, detectionTime time.Time) {
+	if detectionTime.IsZero() {
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[DetectionTimeAnnotation] = detectionTime.UTC().Format(time.RFC3339)
+	obj.SetAnnotations(annotations)
 }
@@ -0,0 +1,161 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TestCreateOrUpdateConfigMap runs against an API server as the fake client
+// does not implement the field ownership of server-side apply.
+func TestCreateOrUpdateConfigMap(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		apiClient = startTestEnv(t)
+		source    = ConfigMapSource{Strategy: "nodeLabel"}
+	)
+	newNamespace := func(t *testing.T) string {
+		t.Helper()
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "payments-"}}
+		require.NoError(t, apiClient.Create(ctx, namespace))
+		return namespace.Name
+	}
+	get := func(t *testing.T, nn types.NamespacedName) *corev1.ConfigMap {
+		t.Helper()
+		var cm corev1.ConfigMap
+		require.NoError(t, apiClient.Get(ctx, nn, &cm))
+		return &cm
+	}
+	// apply applies the data to the ConfigMap as another field manager
+	apply := func(t *testing.T, nn types.NamespacedName, data map[string]string, opts ...client.PatchOption) error {
+		t.Helper()
+		return apiClient.Patch(ctx, &corev1.ConfigMap{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "ConfigMap",
+				APIVersion: "v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nn.Name,
+				Namespace: nn.Namespace,
+			},
+			Data: data,
+		}, client.Apply, opts...)
+	}
+
+	t.Run("Remove keys that are no longer applied", func(t *testing.T) {
+		nn := types.NamespacedName{Namespace: newNamespace(t), Name: "cluster-identity"}
+		err := CreateOrUpdateConfigMap(ctx, apiClient, nn, map[string]string{"clusterName": "prod", "region": "westeurope"}, source)
+		require.NoError(t, err)
+
+		err = CreateOrUpdateConfigMap(ctx, apiClient, nn, map[string]string{"clusterName": "prod"}, source)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"clusterName": "prod"}, get(t, nn).Data)
+	})
+
+	t.Run("Keep keys of other field managers", func(t *testing.T) {
+		nn := types.NamespacedName{Namespace: newNamespace(t), Name: "cluster-identity"}
+		err := CreateOrUpdateConfigMap(ctx, apiClient, nn, map[string]string{"clusterName": "prod"}, source)
+		require.NoError(t, err)
+		err = apply(t, nn, map[string]string{"otherField": "other"}, client.FieldOwner("other-tool"))
+		require.NoError(t, err)
+		cm := get(t, nn)
+		cm.Data["updatedField"] = "updated"
+		require.NoError(t, apiClient.Update(ctx, cm, client.FieldOwner("kubectl")))
+
+		err = CreateOrUpdateConfigMap(ctx, apiClient, nn, map[string]string{"clusterName": "dev"}, source)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"clusterName":  "dev",
+			"otherField":   "other",
+			"updatedField": "updated",
+		}, get(t, nn).Data)
+	})
+
+	t.Run("Take ownership of keys applied by other field managers", func(t *testing.T) {
+		nn := types.NamespacedName{Namespace: newNamespace(t), Name: "cluster-identity"}
+		err := CreateOrUpdateConfigMap(ctx, apiClient, nn, map[string]string{"clusterName": "prod"}, source)
+		require.NoError(t, err)
+		err = apply(t, nn, map[string]string{"clusterName": "other"}, client.FieldOwner("other-tool"))
+		require.True(t, apierrors.IsConflict(err), "expected conflict without force: %v", err)
+		err = apply(t, nn, map[string]string{"clusterName": "other"}, client.FieldOwner("other-tool"), client.ForceOwnership)
+		require.NoError(t, err)
+
+		err = CreateOrUpdateConfigMap(ctx, apiClient, nn, map[string]string{"clusterName": "prod"}, source)
+
+		assert.NoError(t, err)
+		cm := get(t, nn)
+		assert.Equal(t, map[string]string{"clusterName": "prod"}, cm.Data)
+		assert.Equal(t, map[string]bool{"clusterName": true}, appliedKeys(cm, string(FieldOwner)))
+		assert.Empty(t, appliedKeys(cm, "other-tool"))
+	})
+
+	t.Run("Return keys applied by the field manager", func(t *testing.T) {
+		nn := types.NamespacedName{Namespace: newNamespace(t), Name: "cluster-identity"}
+		err := CreateOrUpdateConfigMap(ctx, apiClient, nn, map[string]string{"clusterName": "prod", "region": "westeurope"}, source)
+		require.NoError(t, err)
+		err = apply(t, nn, map[string]string{"otherField": "other"}, client.FieldOwner("other-tool"))
+		require.NoError(t, err)
+
+		cm := get(t, nn)
+
+		assert.Equal(t, map[string]bool{"clusterName": true, "region": true}, appliedKeys(cm, string(FieldOwner)))
+		assert.Equal(t, map[string]bool{"otherField": true}, appliedKeys(cm, "other-tool"))
+	})
+
+	t.Run("Delete ConfigMap only written by the operator", func(t *testing.T) {
+		nn := types.NamespacedName{Namespace: newNamespace(t), Name: "cluster-identity"}
+		err := CreateOrUpdateConfigMap(ctx, apiClient, nn, map[string]string{"clusterName": "prod"}, source)
+		require.NoError(t, err)
+
+		err = DeleteManagedConfigMaps(ctx, apiClient, nn.Namespace)
+
+		assert.NoError(t, err)
+		err = apiClient.Get(ctx, nn, &corev1.ConfigMap{})
+		assert.True(t, apierrors.IsNotFound(err), "expected ConfigMap to be deleted: %v", err)
+	})
+
+	t.Run("Only remove keys of the operator when others own keys", func(t *testing.T) {
+		nn := types.NamespacedName{Namespace: newNamespace(t), Name: "cluster-identity"}
+		err := CreateOrUpdateConfigMap(ctx, apiClient, nn, map[string]string{"clusterName": "prod"}, source)
+		require.NoError(t, err)
+		err = apply(t, nn, map[string]string{"otherField": "other"}, client.FieldOwner("other-tool"))
+		require.NoError(t, err)
+
+		err = DeleteManagedConfigMaps(ctx, apiClient, nn.Namespace)
+
+		assert.NoError(t, err)
+		cm := get(t, nn)
+		assert.Equal(t, map[string]string{"otherField": "other"}, cm.Data)
+		assert.NotContains(t, cm.Labels, ManagedByLabel)
+		assert.NotContains(t, cm.Annotations, StrategyAnnotation)
+	})
+
+	t.Run("Keep moved keys when applying to renamed ConfigMap", func(t *testing.T) {
+		ns := newNamespace(t)
+		old := types.NamespacedName{Namespace: ns, Name: "cluster-identity"}
+		nn := types.NamespacedName{Namespace: ns, Name: "identity"}
+		err := CreateOrUpdateConfigMap(ctx, apiClient, old, map[string]string{"clusterName": "prod"}, source)
+		require.NoError(t, err)
+		err = apply(t, old, map[string]string{"otherField": "other"}, client.FieldOwner("other-tool"))
+		require.NoError(t, err)
+		err = CreateOrUpdateConfigMap(ctx, apiClient, nn, map[string]string{"clusterName": "prod"}, source)
+		require.NoError(t, err)
+		_, err = MigrateConfigMaps(ctx, apiClient, nn, time.Hour, time.Now())
+		require.NoError(t, err)
+
+		err = CreateOrUpdateConfigMap(ctx, apiClient, nn, map[string]string{"clusterName": "dev"}, source)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"clusterName": "dev", "otherField": "other"}, get(t, nn).Data)
+	})
+}
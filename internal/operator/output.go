@@ -0,0 +1,86 @@
+package operator
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Annotations on injected namespaces customizing the managed ConfigMap.
+const (
+	// ConfigMapNameAnnotation overrides the name of the managed ConfigMap.
+	ConfigMapNameAnnotation = "config.lunar.tech/cluster-identity-configmap-name"
+	// KeyPrefixAnnotation is prepended to the keys that are not mapped with
+	// KeyMappingAnnotation.
+	KeyPrefixAnnotation = "config.lunar.tech/cluster-identity-key-prefix"
+	// KeyMappingAnnotation renames keys. It is a comma separated list of
+	// <key>=<new key> pairs, e.g. clusterName=CLUSTER_NAME,region=REGION.
+	KeyMappingAnnotation = "config.lunar.tech/cluster-identity-key-mapping"
+)
+
+// Output describes how the identity is written to the managed ConfigMap of a
+// namespace.
+type Output struct {
+	ConfigMapName string
+	KeyPrefix     string
+	KeyMapping    map[string]string
+}
+
+// OutputForNamespace returns the output configured by the annotations of the
+// namespace. The ConfigMap is named configMapName unless overridden.
+func OutputForNamespace(namespace corev1.Namespace, configMapName string) (Output, error) {
+	output := Output{
+		ConfigMapName: configMapName,
+		KeyPrefix:     namespace.Annotations[KeyPrefixAnnotation],
+	}
+
+	if name, ok := namespace.Annotations[ConfigMapNameAnnotation]; ok {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return Output{}, fmt.Errorf("invalid annotation '%s': %s", ConfigMapNameAnnotation, strings.Join(errs, ", "))
+		}
+		output.ConfigMapName = name
+	}
+
+	mapping := namespace.Annotations[KeyMappingAnnotation]
+	for _, pair := range strings.Split(mapping, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, newKey, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		newKey = strings.TrimSpace(newKey)
+		if !ok || key == "" || newKey == "" {
+			return Output{}, fmt.Errorf("invalid annotation '%s': '%s' is not on the form <key>=<new key>", KeyMappingAnnotation, pair)
+		}
+		if output.KeyMapping == nil {
+			output.KeyMapping = map[string]string{}
+		}
+		output.KeyMapping[key] = newKey
+	}
+
+	return output, nil
+}
+
+// Data returns the data with the keys renamed by the key mapping and prefixed
+// with the key prefix. An error is returned if a resulting key is not a valid
+// ConfigMap key or if two keys are renamed to the same key.
+func (o Output) Data(data map[string]string) (map[string]string, error) {
+	renamed := make(map[string]string, len(data))
+	for key, value := range data {
+		newKey, ok := o.KeyMapping[key]
+		if !ok {
+			newKey = o.KeyPrefix + key
+		}
+		if errs := validation.IsConfigMapKey(newKey); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid key '%s': %s", newKey, strings.Join(errs, ", "))
+		}
+		if _, ok := renamed[newKey]; ok {
+			return nil, fmt.Errorf("more than one key is written to '%s'", newKey)
+		}
+		renamed[newKey] = value
+	}
+	return renamed, nil
+}
@@ -0,0 +1,89 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputForNamespace(t *testing.T) {
+	t.Run("Use default ConfigMap name without annotations", func(t *testing.T) {
+		output, err := OutputForNamespace(namespace("payments", nil, nil), "cluster-identity")
+
+		assert.NoError(t, err)
+		assert.Equal(t, Output{ConfigMapName: "cluster-identity"}, output)
+	})
+
+	t.Run("Read output from annotations", func(t *testing.T) {
+		output, err := OutputForNamespace(namespace("payments", map[string]string{
+			ConfigMapNameAnnotation: "cluster",
+			KeyPrefixAnnotation:     "cluster.",
+			KeyMappingAnnotation:    "clusterName=CLUSTER_NAME, region = REGION",
+		}, nil), "cluster-identity")
+
+		assert.NoError(t, err)
+		assert.Equal(t, Output{
+			ConfigMapName: "cluster",
+			KeyPrefix:     "cluster.",
+			KeyMapping: map[string]string{
+				"clusterName": "CLUSTER_NAME",
+				"region":      "REGION",
+			},
+		}, output)
+	})
+
+	t.Run("Reject invalid ConfigMap name", func(t *testing.T) {
+		_, err := OutputForNamespace(namespace("payments", map[string]string{
+			ConfigMapNameAnnotation: "Cluster_Identity",
+		}, nil), "cluster-identity")
+
+		assert.ErrorContains(t, err, "invalid annotation 'config.lunar.tech/cluster-identity-configmap-name'")
+	})
+
+	t.Run("Reject invalid key mapping", func(t *testing.T) {
+		_, err := OutputForNamespace(namespace("payments", map[string]string{
+			KeyMappingAnnotation: "clusterName=CLUSTER_NAME,region",
+		}, nil), "cluster-identity")
+
+		assert.EqualError(t, err, "invalid annotation 'config.lunar.tech/cluster-identity-key-mapping': 'region' is not on the form <key>=<new key>")
+	})
+}
+
+func TestOutputData(t *testing.T) {
+	data := map[string]string{
+		ClusterNameKey: "prod-weu-01",
+		RegionKey:      "westeurope",
+	}
+
+	t.Run("Keep keys without prefix and mapping", func(t *testing.T) {
+		renamed, err := Output{}.Data(data)
+
+		assert.NoError(t, err)
+		assert.Equal(t, data, renamed)
+	})
+
+	t.Run("Prefix keys that are not mapped", func(t *testing.T) {
+		renamed, err := Output{
+			KeyPrefix:  "cluster.",
+			KeyMapping: map[string]string{ClusterNameKey: "CLUSTER_NAME"},
+		}.Data(data)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"CLUSTER_NAME":   "prod-weu-01",
+			"cluster.region": "westeurope",
+		}, renamed)
+	})
+
+	t.Run("Reject invalid key", func(t *testing.T) {
+		_, err := Output{KeyPrefix: "cluster/"}.Data(data)
+
+		assert.ErrorContains(t, err, "invalid key 'cluster/")
+	})
+
+	t.Run("Reject keys mapped to the same key", func(t *testing.T) {
+		_, err := Output{KeyMapping: map[string]string{ClusterNameKey: "NAME", RegionKey: "NAME"}}.Data(data)
+
+		assert.EqualError(t, err, "more than one key is written to 'NAME'")
+	})
+}
@@ -0,0 +1,123 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Sink is where the identity is written in injected namespaces.
+type Sink string
+
+const (
+	// SinkConfigMap writes the identity to a ConfigMap. It is the default.
+	SinkConfigMap Sink = "configmap"
+	// SinkSecret writes the identity to a Secret instead of a ConfigMap.
+	SinkSecret Sink = "secret"
+	// SinkBoth writes the identity to both a ConfigMap and a Secret.
+	SinkBoth Sink = "both"
+)
+
+// ParseSink returns the sink with the name. An empty name is the ConfigMap
+// sink.
+func ParseSink(name string) (Sink, error) {
+	switch Sink(name) {
+	case "", SinkConfigMap:
+		return SinkConfigMap, nil
+	case SinkSecret, SinkBoth:
+		return Sink(name), nil
+	}
+	return "", fmt.Errorf("unknown sink '%s': valid sinks are %s, %s and %s", name, SinkConfigMap, SinkSecret, SinkBoth)
+}
+
+// ConfigMap returns true if the identity is written to a ConfigMap.
+func (s Sink) ConfigMap() bool {
+	return s != SinkSecret
+}
+
+// Secret returns true if the identity is written to a Secret.
+func (s Sink) Secret() bool {
+	return s == SinkSecret || s == SinkBoth
+}
+
+// CreateOrUpdateSecret applies the data to the Secret with server-side apply.
+// The Secret is managed like the ConfigMap, see CreateOrUpdateConfigMap, and
+// callers must not apply to an existing Secret that is not managed either.
+func CreateOrUpdateSecret(ctx context.Context, apiClient client.Client, nn types.NamespacedName, data map[string]string, source ConfigMapSource) error {
+	secretData := make(map[string][]byte, len(data))
+	for key, value := range data {
+		secretData[key] = []byte(value)
+	}
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nn.Name,
+			Namespace: nn.Namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: secretData,
+	}
+	setManagedMetadata(secret, source)
+	setDetectionTime(secret, source.DetectionTime)
+
+	log.FromContext(ctx).Info(fmt.Sprintf("Applying Secret '%s' with %d keys", nn.String(), len(data)))
+	err := apiClient.Patch(ctx, secret, client.Apply, client.ForceOwnership, FieldOwner)
+	if err != nil {
+		return fmt.Errorf("apply Secret '%s': %w", nn, err)
+	}
+	return nil
+}
+
+// DeleteManagedSecrets removes the Secrets managed by the operator in the
+// namespace like DeleteManagedConfigMaps. Secrets without the managed label are
+// left untouched.
+func DeleteManagedSecrets(ctx context.Context, apiClient client.Client, namespace string) error {
+	secrets, err := listManagedSecrets(ctx, apiClient, namespace)
+	if err != nil {
+		return err
+	}
+	for _, secret := range secrets {
+		err := removeManaged(ctx, apiClient, "Secret", secret)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteRenamedSecrets deletes managed Secrets in the namespace of nn that are
+// not named nn.Name once gracePeriod has passed since they were renamed, like
+// MigrateConfigMaps does for ConfigMaps. Unlike renamed ConfigMaps no keys are
+// moved as the operator owns all keys of the Secret.
+//
+// The returned duration is the time until the next renamed Secret should be
+// deleted. It is zero if there is nothing left to delete.
+func DeleteRenamedSecrets(ctx context.Context, apiClient client.Client, nn types.NamespacedName, gracePeriod time.Duration, now time.Time) (time.Duration, error) {
+	secrets, err := listManagedSecrets(ctx, apiClient, nn.Namespace)
+	if err != nil {
+		return 0, err
+	}
+	return migrateRenamed(ctx, apiClient, "Secret", secrets, nn, gracePeriod, now, nil)
+}
+
+func listManagedSecrets(ctx context.Context, apiClient client.Client, namespace string) ([]*corev1.Secret, error) {
+	var secretList corev1.SecretList
+	err := apiClient.List(ctx, &secretList, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: ManagedConfigMapSelector()})
+	if err != nil {
+		return nil, fmt.Errorf("list managed Secrets in namespace '%s': %w", namespace, err)
+	}
+	secrets := make([]*corev1.Secret, 0, len(secretList.Items))
+	for i := range secretList.Items {
+		secrets = append(secrets, &secretList.Items[i])
+	}
+	return secrets, nil
+}
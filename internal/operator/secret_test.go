@@ -0,0 +1,37 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSink(t *testing.T) {
+	tt := []struct {
+		name      string
+		sink      Sink
+		configMap bool
+		secret    bool
+	}{
+		{name: "", sink: SinkConfigMap, configMap: true},
+		{name: "configmap", sink: SinkConfigMap, configMap: true},
+		{name: "secret", sink: SinkSecret, secret: true},
+		{name: "both", sink: SinkBoth, configMap: true, secret: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sink, err := ParseSink(tc.name)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.sink, sink)
+			assert.Equal(t, tc.configMap, sink.ConfigMap())
+			assert.Equal(t, tc.secret, sink.Secret())
+		})
+	}
+
+	t.Run("Reject unknown sink", func(t *testing.T) {
+		_, err := ParseSink("vault")
+
+		assert.EqualError(t, err, "unknown sink 'vault': valid sinks are configmap, secret and both")
+	})
+}
@@ -0,0 +1,112 @@
+package operator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// TemplatesAnnotation holds per-namespace templates as a YAML map from
+// ConfigMap key to Go template. They take precedence over the templates in
+// the operator config file.
+const TemplatesAnnotation = "config.lunar.tech/cluster-identity-templates"
+
+// templateFuncs are the functions available in templates in addition to the
+// builtin functions.
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// Templates render ConfigMap keys from the identity. The templates are executed
+// with the Identity as data, e.g. {{ .ClusterName }} or
+// {{ .Attributes.resourceGroup }}. Rendering fails if an attribute referenced
+// as a field is missing while {{ index .Attributes "resourceGroup" }} renders
+// an empty string.
+type Templates map[string]*template.Template
+
+// ParseTemplates parses the templates by ConfigMap key. The templates are
+// executed against an empty identity to catch references to unknown fields.
+// Attributes are not known up front so missing attributes are only reported
+// when the templates are rendered.
+func ParseTemplates(templates map[string]string) (Templates, error) {
+	parsed := make(Templates, len(templates))
+	for _, key := range sortedKeys(templates) {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return nil, fmt.Errorf("template '%s': invalid key: %s", key, strings.Join(errs, ", "))
+		}
+		tmpl, err := template.New(key).Funcs(templateFuncs).Option("missingkey=error").Parse(templates[key])
+		if err != nil {
+			return nil, fmt.Errorf("template '%s': %w", key, err)
+		}
+		dryRun, err := tmpl.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("template '%s': %w", key, err)
+		}
+		err = dryRun.Option("missingkey=zero").Execute(&bytes.Buffer{}, Identity{})
+		if err != nil {
+			return nil, fmt.Errorf("template '%s': %w", key, err)
+		}
+		parsed[key] = tmpl
+	}
+	return parsed, nil
+}
+
+// TemplatesForNamespace returns the templates with the templates of the
+// namespace annotation added.
+func (t Templates) TemplatesForNamespace(namespace corev1.Namespace) (Templates, error) {
+	annotation, ok := namespace.Annotations[TemplatesAnnotation]
+	if !ok {
+		return t, nil
+	}
+
+	var templates map[string]string
+	err := yaml.UnmarshalStrict([]byte(annotation), &templates)
+	if err != nil {
+		return nil, fmt.Errorf("invalid annotation '%s': %w", TemplatesAnnotation, err)
+	}
+	namespaceTemplates, err := ParseTemplates(templates)
+	if err != nil {
+		return nil, fmt.Errorf("invalid annotation '%s': %w", TemplatesAnnotation, err)
+	}
+
+	merged := make(Templates, len(t)+len(namespaceTemplates))
+	for key, tmpl := range t {
+		merged[key] = tmpl
+	}
+	for key, tmpl := range namespaceTemplates {
+		merged[key] = tmpl
+	}
+	return merged, nil
+}
+
+// Render executes the templates against the identity and returns the rendered
+// values by ConfigMap key.
+func (t Templates) Render(identity Identity) (map[string]string, error) {
+	data := make(map[string]string, len(t))
+	for _, key := range sortedKeys(t) {
+		var rendered bytes.Buffer
+		err := t[key].Execute(&rendered, identity)
+		if err != nil {
+			return nil, fmt.Errorf("render template '%s': %w", key, err)
+		}
+		data[key] = rendered.String()
+	}
+	return data, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
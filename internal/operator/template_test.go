@@ -0,0 +1,130 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTemplates(t *testing.T) {
+	t.Run("Reject invalid template", func(t *testing.T) {
+		_, err := ParseTemplates(map[string]string{
+			"otel": "k8s.cluster.name={{ .ClusterName",
+		})
+
+		assert.ErrorContains(t, err, "template 'otel': template: otel:1: unclosed action")
+	})
+
+	t.Run("Reject unknown field", func(t *testing.T) {
+		_, err := ParseTemplates(map[string]string{
+			"otel": "k8s.cluster.name={{ .Name }}",
+		})
+
+		assert.ErrorContains(t, err, "template 'otel': template: otel:1:20: executing \"otel\" at <.Name>: can't evaluate field Name")
+	})
+
+	t.Run("Accept attribute field", func(t *testing.T) {
+		_, err := ParseTemplates(map[string]string{
+			"resourceGroup": "{{ .Attributes.resourceGroup }}",
+		})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Reject invalid key", func(t *testing.T) {
+		_, err := ParseTemplates(map[string]string{
+			"otel/attributes": "{{ .ClusterName }}",
+		})
+
+		assert.ErrorContains(t, err, "template 'otel/attributes': invalid key")
+	})
+}
+
+func TestTemplatesRender(t *testing.T) {
+	identity := Identity{
+		ClusterName: "prod-weu-01",
+		Region:      "westeurope",
+		Zones:       []string{"1", "2"},
+		Attributes:  map[string]string{AttributeResourceGroup: "lunar-prod"},
+	}
+
+	t.Run("Render templates against identity", func(t *testing.T) {
+		templates, err := ParseTemplates(map[string]string{
+			"otel.resource.attributes": "k8s.cluster.name={{ .ClusterName }},cloud.region={{ .Region }}",
+			"application.properties":   "cluster.name={{ upper .ClusterName }}\ncluster.zones={{ join .Zones \",\" }}\ncluster.resource-group={{ index .Attributes \"resourceGroup\" }}",
+		})
+		assert.NoError(t, err)
+
+		data, err := templates.Render(identity)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"otel.resource.attributes": "k8s.cluster.name=prod-weu-01,cloud.region=westeurope",
+			"application.properties":   "cluster.name=PROD-WEU-01\ncluster.zones=1,2\ncluster.resource-group=lunar-prod",
+		}, data)
+	})
+
+	t.Run("Render attribute field", func(t *testing.T) {
+		templates, err := ParseTemplates(map[string]string{
+			"resourceGroup": "{{ .Attributes.resourceGroup }}",
+		})
+		assert.NoError(t, err)
+
+		data, err := templates.Render(identity)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"resourceGroup": "lunar-prod"}, data)
+	})
+
+	t.Run("Reject missing attribute field", func(t *testing.T) {
+		templates, err := ParseTemplates(map[string]string{
+			"subscription": "{{ .Attributes.subscription }}",
+		})
+		assert.NoError(t, err)
+
+		_, err = templates.Render(identity)
+
+		assert.ErrorContains(t, err, "render template 'subscription': template: subscription:1:14: executing \"subscription\" at <.Attributes.subscription>: map has no entry for key \"subscription\"")
+	})
+
+	t.Run("Render missing attribute with index as empty", func(t *testing.T) {
+		templates, err := ParseTemplates(map[string]string{
+			"subscription": "{{ index .Attributes \"subscription\" }}",
+		})
+		assert.NoError(t, err)
+
+		data, err := templates.Render(identity)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"subscription": ""}, data)
+	})
+
+	t.Run("Add namespace templates", func(t *testing.T) {
+		templates, err := ParseTemplates(map[string]string{
+			"name":   "{{ .ClusterName }}",
+			"region": "{{ .Region }}",
+		})
+		assert.NoError(t, err)
+
+		templates, err = templates.TemplatesForNamespace(namespace("payments", map[string]string{
+			TemplatesAnnotation: "name: cluster-{{ .ClusterName }}\nzones: '{{ join .Zones \" \" }}'\n",
+		}, nil))
+		assert.NoError(t, err)
+		data, err := templates.Render(identity)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"name":   "cluster-prod-weu-01",
+			"region": "westeurope",
+			"zones":  "1 2",
+		}, data)
+	})
+
+	t.Run("Reject invalid namespace templates", func(t *testing.T) {
+		_, err := Templates{}.TemplatesForNamespace(namespace("payments", map[string]string{
+			TemplatesAnnotation: "name: '{{ .Name }}'",
+		}, nil))
+
+		assert.ErrorContains(t, err, "invalid annotation 'config.lunar.tech/cluster-identity-templates': template 'name'")
+	})
+}
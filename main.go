@@ -19,19 +19,29 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/lunarway/cluster-identity-controller/internal/operator"
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	configv1alpha1 "github.com/lunarway/cluster-identity-controller/apis/config/v1alpha1"
+	configcontrollers "github.com/lunarway/cluster-identity-controller/controllers/config"
 	corecontrollers "github.com/lunarway/cluster-identity-controller/controllers/core"
 	//+kubebuilder:scaffold:imports
 )
@@ -44,6 +54,7 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
+	utilruntime.Must(configv1alpha1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -52,12 +63,36 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var configMapKey string
+	var clusterIdentityName string
+	var resyncPeriod time.Duration
+	var gkeMetadataEndpoint string
+	var strategyFallthrough bool
+	var strategies string
+	var operatorConfigFile string
+	var quorum int
+	var keepConfigMaps bool
+	var migrationGracePeriod time.Duration
+	var namespaceSelector string
+	var excludeNamespaces string
+	var sinkName string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&configMapKey, "managed-config-map", "cluster-identity", "The name of the managed ConfigMap that is to be created in injectable namespaces.")
+	flag.StringVar(&clusterIdentityName, "cluster-identity", "cluster", "The name of the ClusterIdentity object holding the detected cluster identity.")
+	flag.DurationVar(&resyncPeriod, "identity-resync-period", 5*time.Minute, "How often the cluster identity is detected again.")
+	flag.StringVar(&gkeMetadataEndpoint, "gke-metadata-endpoint", "", "The base URL of the GCE metadata server used to detect the cluster name on GKE, e.g. http://metadata.google.internal. Disabled when empty.")
+	flag.BoolVar(&strategyFallthrough, "strategy-fallthrough", false, "Continue with the next strategy when a strategy fails. The errors of all strategies are reported if no strategy detects the cluster name.")
+	flag.StringVar(&strategies, "strategies", "", "Comma separated list of the strategies to try in order, e.g. nodeLabel,kubeadm. Overrides the strategies in the operator config file. Defaults to "+strings.Join(operator.DefaultStrategies, ",")+".")
+	flag.StringVar(&operatorConfigFile, "operator-config", "", "Path to the operator config file.")
+	flag.StringVar(&namespaceSelector, "namespace-selector", "", "Label selector of namespaces to inject in addition to namespaces with the injection annotation, e.g. 'team,env in (prod,staging)'.")
+	flag.StringVar(&excludeNamespaces, "exclude-namespaces", "", "Comma separated list of namespace name patterns that are never injected, e.g. 'kube-*,default'.")
+	flag.StringVar(&sinkName, "sink", string(operator.SinkConfigMap), "Where the identity is written in injected namespaces: configmap, secret or both.")
+	flag.DurationVar(&migrationGracePeriod, "managed-config-map-migration-grace-period", 24*time.Hour, "How long managed ConfigMaps are kept after their data is moved to the ConfigMap named by --managed-config-map.")
+	flag.BoolVar(&keepConfigMaps, "keep-config-map-on-opt-out", false, "Keep the managed ConfigMap when a namespace opts out of injection instead of deleting it.")
+	flag.IntVar(&quorum, "strategy-quorum", 0, "Enable consensus mode where all strategies are run and at least this many must detect the same cluster name. Overrides the quorum in the operator config file. Disabled when 0.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -66,6 +101,52 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	var operatorConfig operator.Config
+	if operatorConfigFile != "" {
+		var err error
+		operatorConfig, err = operator.LoadConfig(operatorConfigFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load operator config")
+			os.Exit(1)
+		}
+	}
+	if strategies != "" {
+		operatorConfig.Strategies = strings.Split(strategies, ",")
+	}
+	if quorum != 0 {
+		operatorConfig.Quorum = quorum
+	}
+
+	templates, err := operator.ParseTemplates(operatorConfig.Templates)
+	if err != nil {
+		setupLog.Error(err, "unable to parse templates")
+		os.Exit(1)
+	}
+
+	sink, err := operator.ParseSink(sinkName)
+	if err != nil {
+		setupLog.Error(err, "unable to parse sink")
+		os.Exit(1)
+	}
+
+	namespaceFilter, err := operator.NewNamespaceFilter(namespaceSelector, excludeNamespaces)
+	if err != nil {
+		setupLog.Error(err, "unable to create namespace filter")
+		os.Exit(1)
+	}
+
+	clusterNameFinder, err := operator.NewClusterNameFinder(operator.ClusterNameFinderOptions{
+		Strategies:          operatorConfig.Strategies,
+		GKEMetadataEndpoint: gkeMetadataEndpoint,
+		Fallthrough:         strategyFallthrough,
+		Quorum:              operatorConfig.Quorum,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to create cluster name finder")
+		os.Exit(1)
+	}
+
+	kubeSystemSelector := fields.OneTermEqualSelector("metadata.namespace", metav1.NamespaceSystem)
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -73,6 +154,19 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "d77ffa94.lunar.tech",
+		// only managed ConfigMaps and Secrets are cached to keep the cache
+		// small and other Secrets out of memory. Secrets are only cached
+		// when the sink includes Secrets. The strategies only read pods,
+		// deployments and daemonsets in kube-system so only those are cached.
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.ConfigMap{}:  {Label: operator.ManagedConfigMapSelector()},
+				&corev1.Secret{}:     {Label: operator.ManagedConfigMapSelector()},
+				&corev1.Pod{}:        {Field: kubeSystemSelector},
+				&appsv1.Deployment{}: {Field: kubeSystemSelector},
+				&appsv1.DaemonSet{}:  {Field: kubeSystemSelector},
+			},
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -80,13 +174,45 @@ func main() {
 	}
 
 	if err = (&corecontrollers.NamespaceReconciler{
-		Client:            mgr.GetClient(),
-		ConfigMapKey:      configMapKey,
-		ClusterNameFinder: operator.NewClusterNameFinder(),
+		Client:               mgr.GetClient(),
+		APIReader:            mgr.GetAPIReader(),
+		ConfigMapKey:         configMapKey,
+		ClusterIdentityName:  clusterIdentityName,
+		NamespaceFilter:      namespaceFilter,
+		Templates:            templates,
+		Sink:                 sink,
+		Recorder:             mgr.GetEventRecorderFor("namespace-controller"),
+		KeepConfigMaps:       keepConfigMaps,
+		MigrationGracePeriod: migrationGracePeriod,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Namespace")
 		os.Exit(1)
 	}
+	// kubeadm-config is not labelled as managed and not in the cache of the
+	// manager, so the strategies read ConfigMaps directly from the API server.
+	identityClient, err := client.New(mgr.GetConfig(), client.Options{
+		HTTPClient: mgr.GetHTTPClient(),
+		Scheme:     mgr.GetScheme(),
+		Mapper:     mgr.GetRESTMapper(),
+		Cache: &client.CacheOptions{
+			Reader:     mgr.GetCache(),
+			DisableFor: []client.Object{&corev1.ConfigMap{}},
+		},
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to create client", "controller", "ClusterIdentity")
+		os.Exit(1)
+	}
+	if err = (&configcontrollers.ClusterIdentityReconciler{
+		Client:              identityClient,
+		ClusterIdentityName: clusterIdentityName,
+		ClusterNameFinder:   clusterNameFinder,
+		ResyncPeriod:        resyncPeriod,
+		Recorder:            mgr.GetEventRecorderFor("clusteridentity-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterIdentity")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {